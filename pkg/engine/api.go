@@ -1,6 +1,9 @@
 package engine
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -23,6 +26,10 @@ type DataRequest struct {
 	RequestDestination  ID
 	AssociatedBuildTime map[ID]time.Time
 	KeyIDPairs
+	//Attempt number of times the request already failed
+	Attempt int
+	//Hops number of members that relayed the request toward RequestDestination
+	Hops int
 }
 
 type DataResponse struct {
@@ -30,9 +37,24 @@ type DataResponse struct {
 	Items               Items
 }
 
+//DataPush carries items that Origin just wrote. Each member relays it to its neighbors until Hops reaches zero
+//and drops the pushes it has already seen. Sequence is unique per Origin.
+//When Leave is set the push carries no items and announces that Origin leaves the mesh
+type DataPush struct {
+	Origin   ID
+	Sequence uint64
+	Sender   ID
+	Hops     int
+	Items    Items
+	Leave    bool
+}
+
+//StampedKey identifies a version of an item. Expires is set by the owner for the items that must disappear after
+//a while, the zero value never expires
 type StampedKey struct {
 	Key
 	Timestamp time.Time
+	Expires   time.Time
 }
 
 type StampedKeys []StampedKey
@@ -58,14 +80,109 @@ func (a StampedKeys) Equal(b StampedKeys) bool {
 	return true
 }
 
+//IndexMode tells how the keys of an owner are described by an Index
+type IndexMode int
+
+const (
+	//IndexModeFull the Index lists all the StampedKeys of the owner
+	IndexModeFull IndexMode = iota
+	//IndexModeMerkle the Index only carries the MerkleRoot, the differing subtrees are fetched with IndexRequests
+	IndexModeMerkle
+	//IndexModeDelta the Index only carries the BuildTime, the changes since a known BuildTime are fetched with IndexRequests
+	IndexModeDelta
+)
+
+//Index describes the keys of an owner at BuildTime. When Retired is set the owner left the mesh for good:
+//the Index carries no keys and the members purge the data of the owner known at BuildTime.
+//Digest is the hash of all the keys of the owner held by the sender, whatever the mode and the pruning of the Index.
+//MerkleDepth is the depth of the tree of MerkleRoot, the receiver builds its own tree with the same depth
 type Index struct {
 	BuildTime   time.Time
 	StampedKeys StampedKeys
+	Mode        IndexMode
+	MerkleRoot  Hash
+	MerkleDepth int
+	Retired     bool
+	Digest      Hash
 }
 
+//Digest summarizes the view of a member on the shard of an owner: the BuildTime of the version it holds and
+//the hash of the keys. Two members holding the same versions of the keys have the same Hash
+type Digest struct {
+	BuildTime time.Time
+	Hash      Hash
+}
+
+//Digests are the views of a member on the shards of all the owners
+type Digests map[ID]Digest
+
+//IndexMap is sent by Source to its neighbors. Clock is the time of Source when it was sent, observed by the receivers.
+//Interests are the interests of the members that Source serves, itself included. When Scope is set the indexes were
+//pruned: they only list the owners and the keys matching one of its interests. A set Scope is never empty, so that a
+//connector may serialize nil and empty alike. Handovers are applied before the indexes
 type IndexMap struct {
-	Source  ID
-	Indexes map[ID]Index
+	Source    ID
+	Indexes   map[ID]Index
+	Clock     time.Time
+	Interests map[ID]MemberInterest
+	Scope     []Interest
+	Handovers []Handover
+}
+
+//Handover transfers the ownership of the versions Keys from From to To. In every member the items move from the
+//shard of From to the shard of To, stamped at Time
+type Handover struct {
+	From ID
+	To   ID
+	Time time.Time
+	Keys StampedKeys
+}
+
+//Interest selects the data needed by a member: the items of Owners whose key starts with one of KeyPrefixes.
+//Empty lists select all the owners and all the keys. Predicate refines the selection of the local member,
+//it is not advertised: the neighbors still send the items it rejects
+type Interest struct {
+	Owners      []ID
+	KeyPrefixes []Key
+	Predicate   func(KeyIDPair) bool
+}
+
+//MemberInterest is the Interest of a member relayed in the IndexMaps. Version is the clock of the member when it
+//declared it, the newest version wins. Via is the neighbor from which the Source of the IndexMap learnt it
+type MemberInterest struct {
+	Interest
+	Version time.Time
+	Via     ID
+}
+
+//IndexRequest asks RequestDestination for the details of the indexes it advertised.
+//MerkleNodes are read from the trees identified by MerkleRoots so that all the rounds of a comparison see the same tree
+type IndexRequest struct {
+	RequestSource      ID
+	RequestDestination ID
+	MerkleRoots        map[ID]Hash
+	MerkleNodes        map[ID][]MerkleNodeID
+	Since              map[ID]time.Time
+}
+
+//IndexResponse is built by Source to answer the IndexRequest of RequestSource
+type IndexResponse struct {
+	RequestSource ID
+	Source        ID
+	BuildTime     map[ID]time.Time
+	MerkleRoots   map[ID]Hash
+	MerkleDepths  map[ID]int
+	MerkleNodes   map[ID][]MerkleNode
+	Deltas        map[ID]IndexDelta
+}
+
+//IndexDelta lists the keys of an owner that changed between Since and the BuildTime of the IndexResponse.
+//If the history of the responder does not go back to Since, Full is set and StampedKeys lists all the keys of the owner
+type IndexDelta struct {
+	Since       time.Time
+	Full        bool
+	StampedKeys StampedKeys
+	Deleted     Keys
 }
 
 type Member interface {
@@ -74,30 +191,100 @@ type Member interface {
 	GetData(KeyIDPairs) Items
 }
 
+//ErrUnknownMember is returned by a ConnectorCore asked to reach a member it is not connected to
+var ErrUnknownMember = errors.New("unknown member")
+
+//ErrDisconnected is returned for the operations canceled because the member was disconnected
+var ErrDisconnected = errors.New("member disconnected")
+
+//ErrNoRoute is returned for the DataRequests whose destination has no route, or relayed more than MaxRouteHops times,
+//caught in a routing loop
+var ErrNoRoute = errors.New("no route")
+
+//ErrStopped is returned by a ConnectorCore that can't deliver a message because its connector is stopped
+var ErrStopped = errors.New("connector stopped")
+
+//ErrPushDropped is reported when a push is not sent because the engine is stopped or the connector queue is full
+var ErrPushDropped = errors.New("push dropped")
+
+//ErrIndexExchangeDropped is reported when an IndexRequest or an IndexResponse is not sent because the engine is stopped
+//or the connector queue is full. The next IndexMap of the neighbor starts the exchange again
+var ErrIndexExchangeDropped = errors.New("index exchange dropped")
+
+//ConnectorError reports the failure of a ConnectorCore operation run by the Connector. Request is set when a DataRequest failed
+type ConnectorError struct {
+	Op      string
+	Request *DataRequest
+	Err     error
+}
+
+func (e *ConnectorError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *ConnectorError) Unwrap() error {
+	return e.Err
+}
+
 type ConnectorCore interface {
 	GetLocalMember() LocalMember
-	Connect(Member)
-	ForwardDataRequest(rq DataRequest)
-	ProcessDataRequest(rq DataRequest)
-	ProcessIndexMap(index IndexMap)
+	Connect(Member) error
+	Disconnect(ID) error
+	//Peers returns the members the IndexMaps are sent to
+	Peers() []ID
+	//ForwardDataRequest asks the neighbor hop for the items of rq and hands over the response to the engine. The
+	//neighbor serves rq with ConnectorImpl.ServeDataRequest: it relays rq when it is not the RequestDestination
+	ForwardDataRequest(hop ID, rq DataRequest) error
+	ProcessDataRequest(rq DataRequest) error
+	//ProcessIndexMap sends the IndexMap to all the peers, ForwardIndexMap to the peer id only
+	ProcessIndexMap(index IndexMap) error
+	ForwardIndexMap(id ID, index IndexMap) error
+	ForwardIndexRequest(rq IndexRequest) error
+	ProcessIndexResponse(rs IndexResponse) error
+	ProcessDataPush(p DataPush) error
 }
 type ConnectorChan interface {
 	ReceiveIndexChan() <-chan IndexMap
 	SendIndexChan() chan<- IndexMap
 	RequestKeysChan() chan<- DataRequest
 	ReceiveDataChan() <-chan DataResponse
+	RequestIndexChan() chan<- IndexRequest
+	ReceiveIndexRequestChan() <-chan IndexRequest
+	SendIndexResponseChan() chan<- IndexResponse
+	ReceiveIndexResponseChan() <-chan IndexResponse
+	PushChan() chan<- DataPush
+	ReceivePushChan() <-chan DataPush
+	ReceiveWrittenChan() <-chan Items
+	ErrorChan() <-chan error
 }
 type Connector interface {
 	ConnectorChan
 	ConnectorCore
-	Run(stop <-chan struct{})
+	Run(ctx context.Context)
+	//SetRouter sets the routes used to reach the members that are not neighbors
+	SetRouter(Router)
+	//Written notifies the engine of the items just written by the local member
+	Written(Items)
+	//GossipPeriods returns the mean number of sync periods between two IndexMaps sent to the same peer
+	GossipPeriods() int
+}
+
+//Router returns the neighbor to which a message for destination is sent, false if destination can't be reached.
+//The engine requests the keys to the neighbors that advertised them, which hold them one hop away: a DataRequest only
+//takes a route as a fallback, toward the owner of the keys, once all these neighbors failed
+type Router interface {
+	NextHop(destination ID) (ID, bool)
 }
 
 type LocalMember interface {
 	Member
 	Delete(KeyIDPairs)
 	Put(Items)
+	//PurgeOwner removes all the data of a retired owner, its index must no longer be listed
+	PurgeOwner(ID)
 	GetConnector() Connector
+	//Clock stamps the items written by the local member, the engine uses it for the BuildTime of the indexes
+	Clock() Clock
 }
 
 type Items []Item
@@ -108,3 +295,9 @@ type Item interface {
 	OwnedBy() ID
 	DeepCopy() Item
 }
+
+//Codec serializes Items when they have to leave the process (RPC, disk)
+type Codec interface {
+	Marshal(Item) ([]byte, error)
+	Unmarshal([]byte) (Item, error)
+}
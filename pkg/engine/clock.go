@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+//Clock provides the timestamps of the items and the BuildTime of the indexes
+type Clock interface {
+	//Now returns a timestamp greater than all the timestamps returned or observed before
+	Now() time.Time
+	//Observe merges a timestamp received from another member
+	Observe(time.Time)
+}
+
+//WallClock is the system clock. The timestamps of different hosts are only comparable if their clocks are
+//synchronized, and a clock set back makes the new versions look older
+type WallClock struct{}
+
+var _ Clock = WallClock{}
+
+func (WallClock) Now() time.Time {
+	return time.Now().Round(0)
+}
+
+func (WallClock) Observe(time.Time) {}
+
+//HybridClock is a hybrid logical clock. It follows the physical clock but never goes back: when the physical
+//clock is behind the last timestamp returned or observed, the logical part is incremented. Both parts are packed
+//in a time.Time, the logical counter using the nanoseconds, so the timestamps compare and serialize as usual
+type HybridClock struct {
+	sync.Mutex
+	last      time.Time
+	maxOffset time.Duration
+	physical  func() time.Time
+}
+
+var _ Clock = &HybridClock{}
+
+//NewHybridClock returns a hybrid logical clock. The observed timestamps more than maxOffset ahead of the physical
+//clock are ignored so that a host with a wrong clock can't drag the mesh into the future; 0 accepts all of them
+func NewHybridClock(maxOffset time.Duration) *HybridClock {
+	return &HybridClock{maxOffset: maxOffset, physical: time.Now}
+}
+
+func (c *HybridClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	pt := c.physical().Round(0)
+	if pt.After(c.last) {
+		c.last = pt
+	} else {
+		c.last = c.last.Add(time.Nanosecond)
+	}
+	return c.last
+}
+
+func (c *HybridClock) Observe(t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	if c.maxOffset > 0 && t.Sub(c.physical()) > c.maxOffset {
+		return
+	}
+	if t.After(c.last) {
+		c.last = t.Round(0)
+	}
+}
+
+//DefaultMaxClockOffset is the maxOffset of the HybridClock of the members of the mesh
+const DefaultMaxClockOffset = time.Minute
+
+//StampableItem is implemented by the items that a LocalMember stamps with its clock when they are written
+type StampableItem interface {
+	Item
+	//Stamp returns a copy of the item stamped at t
+	Stamp(t time.Time) Item
+}
+
+//ErrNotStampable is returned by a LocalMember asked to write an item that does not implement StampableItem
+var ErrNotStampable = errors.New("item not stampable")
+
+//Clock returns the clock of the local member, used by the engine for the BuildTime of the indexes
+func (e *Engine) Clock() Clock {
+	return e.clock
+}
+
+//observeItems merges the stamps of the items received from the other members
+func (e *Engine) observeItems(items Items) {
+	for _, i := range items {
+		e.clock.Observe(i.StampedKey().Timestamp)
+	}
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHybridClockMonotonic(t *testing.T) {
+	physical := time.Now()
+	c := NewHybridClock(0)
+	c.physical = func() time.Time { return physical }
+	t1 := c.Now()
+	// the physical clock is set back
+	physical = physical.Add(-time.Hour)
+	t2 := c.Now()
+	if !t2.After(t1) {
+		t.Fatalf("the clock went back: %v then %v", t1, t2)
+	}
+	if t2.Sub(t1) != time.Nanosecond {
+		t.Fatalf("expected a logical increment, got %v", t2.Sub(t1))
+	}
+	// the physical clock catches up
+	physical = t1.Add(time.Second)
+	if t3 := c.Now(); !t3.Equal(physical) {
+		t.Fatalf("expected the physical time %v, got %v", physical, t3)
+	}
+}
+
+func TestHybridClockObserve(t *testing.T) {
+	physical := time.Now()
+	c := NewHybridClock(time.Minute)
+	c.physical = func() time.Time { return physical }
+
+	// a member whose clock is ahead
+	remote := physical.Add(10 * time.Second)
+	c.Observe(remote)
+	if t1 := c.Now(); !t1.After(remote) {
+		t.Fatalf("a timestamp after the observed one was expected, got %v <= %v", t1, remote)
+	}
+
+	// a member whose clock is wrong is ignored
+	wrong := physical.Add(time.Hour)
+	c.Observe(wrong)
+	if t2 := c.Now(); t2.After(wrong) {
+		t.Fatalf("a timestamp beyond the max offset was observed")
+	}
+}
+
+func TestSkewedOwnerClock(t *testing.T) {
+	// the clock of M0 is set back by an hour after its first write
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	hc := members[0].clock.(*HybridClock)
+	var physical int64 // read by the engine while the test moves it
+	atomic.StoreInt64(&physical, time.Now().UnixNano())
+	hc.physical = func() time.Time { return time.Unix(0, atomic.LoadInt64(&physical)) }
+
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	members[0].Write(newTestItem("David", "Benque"))
+	waitForCount(t, 1, members, checkPeriod, 2*time.Second)
+
+	atomic.AddInt64(&physical, -int64(time.Hour))
+	members[0].Write(newTestItem("David", "dbenque"))
+	waitForCheck(members, checkPeriod, KeyIDPair{Key: "David", ID: members[0].id},
+		func(i Item) bool {
+			return i != nil && i.(*testItem).Value == "dbenque"
+		}, 2*time.Second)
+}
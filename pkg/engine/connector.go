@@ -1,25 +1,111 @@
 package engine
 
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
 type ConnectorImpl struct {
 	ConnectorCore
 	ReceiveIndexCh chan IndexMap
 	sendIndexCh    chan IndexMap
 	RequestKeysCh  chan DataRequest
 	ReceiveDataCh  chan DataResponse
+
+	RequestIndexCh         chan IndexRequest
+	ReceiveIndexRequestCh  chan IndexRequest
+	sendIndexResponseCh    chan IndexResponse
+	ReceiveIndexResponseCh chan IndexResponse
+
+	pushCh        chan DataPush
+	ReceivePushCh chan DataPush
+	writtenCh     chan Items
+
+	errorCh       chan error
+	droppedErrors uint64
+	done          chan struct{}
+
+	workers    int
+	queueSize  int
+	jobs       chan job
+	busy       int64
+	running    sync.WaitGroup
+	peersMutex sync.Mutex
+	peers      map[ID]*peerQueue
+	gossip     GossipStrategy
+
+	peerInflight int
+	slotsMutex   sync.Mutex
+	requests     map[ID]*inflightSlots
+	pushes       inflightSlots
+
+	routerMutex sync.RWMutex
+	router      Router
 }
 
 var _ Connector = &ConnectorImpl{}
 
 type ConnectorCoreFactory func(localMember LocalMember, connectorChan ConnectorChan) ConnectorCore
 
-func NewConnector(localMember LocalMember, coreFactory ConnectorCoreFactory) *ConnectorImpl {
-	impl := &ConnectorImpl{
-		ReceiveIndexCh: make(chan IndexMap, 50),
-		sendIndexCh:    make(chan IndexMap, 50),
+//ConnectorOption configures a ConnectorImpl
+type ConnectorOption func(*ConnectorImpl)
+
+//DefaultConnectorWorkers is the number of ConnectorCore operations run in parallel
+const DefaultConnectorWorkers = 16
+
+//DefaultConnectorQueueSize is the capacity of the channels between the engine and the connector
+const DefaultConnectorQueueSize = 50
+
+//WithConnectorWorkers sets the number of ConnectorCore operations run in parallel. Default is DefaultConnectorWorkers
+func WithConnectorWorkers(workers int) ConnectorOption {
+	return func(c *ConnectorImpl) {
+		c.workers = workers
+	}
+}
 
-		ReceiveDataCh: make(chan DataResponse, 50),
-		RequestKeysCh: make(chan DataRequest, 50),
+//WithConnectorQueueSize sets the capacity of the channels between the engine and the connector, and of the queue of
+//the operations waiting for a worker. Default is DefaultConnectorQueueSize
+func WithConnectorQueueSize(size int) ConnectorOption {
+	return func(c *ConnectorImpl) {
+		c.queueSize = size
 	}
+}
+
+func NewConnector(localMember LocalMember, coreFactory ConnectorCoreFactory, options ...ConnectorOption) *ConnectorImpl {
+	impl := &ConnectorImpl{
+		workers:      DefaultConnectorWorkers,
+		queueSize:    DefaultConnectorQueueSize,
+		peerInflight: DefaultPeerInflight,
+		peers:        map[ID]*peerQueue{},
+		requests:     map[ID]*inflightSlots{},
+		gossip:       Broadcast(),
+		done:         make(chan struct{}),
+	}
+	for _, option := range options {
+		option(impl)
+	}
+	if impl.workers < 1 {
+		impl.workers = 1
+	}
+	if impl.peerInflight < 1 {
+		impl.peerInflight = 1
+	}
+	size := impl.queueSize
+	impl.ReceiveIndexCh = make(chan IndexMap, size)
+	impl.sendIndexCh = make(chan IndexMap, size)
+	impl.ReceiveDataCh = make(chan DataResponse, size)
+	impl.RequestKeysCh = make(chan DataRequest, size)
+	impl.RequestIndexCh = make(chan IndexRequest, size)
+	impl.ReceiveIndexRequestCh = make(chan IndexRequest, size)
+	impl.sendIndexResponseCh = make(chan IndexResponse, size)
+	impl.ReceiveIndexResponseCh = make(chan IndexResponse, size)
+	impl.pushCh = make(chan DataPush, size)
+	impl.ReceivePushCh = make(chan DataPush, size)
+	impl.writtenCh = make(chan Items, size)
+	impl.errorCh = make(chan error, size)
+	impl.jobs = make(chan job, size)
 	impl.ConnectorCore = coreFactory(localMember, impl)
 	return impl
 }
@@ -38,20 +124,125 @@ func (c *ConnectorImpl) ReceiveDataChan() <-chan DataResponse {
 	return c.ReceiveDataCh
 }
 
-func (c *ConnectorImpl) Run(stop <-chan struct{}) {
+func (c *ConnectorImpl) RequestIndexChan() chan<- IndexRequest {
+	return c.RequestIndexCh
+}
+func (c *ConnectorImpl) ReceiveIndexRequestChan() <-chan IndexRequest {
+	return c.ReceiveIndexRequestCh
+}
+func (c *ConnectorImpl) SendIndexResponseChan() chan<- IndexResponse {
+	return c.sendIndexResponseCh
+}
+func (c *ConnectorImpl) ReceiveIndexResponseChan() <-chan IndexResponse {
+	return c.ReceiveIndexResponseCh
+}
+
+func (c *ConnectorImpl) PushChan() chan<- DataPush {
+	return c.pushCh
+}
+func (c *ConnectorImpl) ReceivePushChan() <-chan DataPush {
+	return c.ReceivePushCh
+}
+func (c *ConnectorImpl) ReceiveWrittenChan() <-chan Items {
+	return c.writtenCh
+}
+
+//Written notifies the engine of the items just written by the local member, so that it pushes them. It never blocks
+//the writer: the notification is dropped when the engine does not keep up, the index sync still propagates the items
+func (c *ConnectorImpl) Written(items Items) {
+	select {
+	case c.writtenCh <- items:
+	default:
+	}
+}
+
+//ErrorChan delivers the failures of the ConnectorCore operations as *ConnectorError
+func (c *ConnectorImpl) ErrorChan() <-chan error {
+	return c.errorCh
+}
+
+//do runs the ConnectorCore operation and reports its failure on the error channel
+func (c *ConnectorImpl) do(op string, rq *DataRequest, f func() error) {
+	if err := f(); err != nil {
+		c.report(&ConnectorError{Op: op, Request: rq, Err: err})
+	}
+}
+
+//report sends the failure of an operation on the error channel. It never blocks: the failure is dropped and
+//counted when the channel is full, the next index sync issues the request again
+func (c *ConnectorImpl) report(err *ConnectorError) {
+	select {
+	case c.errorCh <- err:
+	default:
+		atomic.AddUint64(&c.droppedErrors, 1)
+	}
+}
+
+//Done returns a channel closed when Run stops. The ConnectorCore must not block on the channels of the connector
+//after it: nobody reads them anymore. A connector is run once
+func (c *ConnectorImpl) Done() <-chan struct{} {
+	return c.done
+}
+
+//Run dispatches the messages of the engine until ctx is done, then waits for the operations in flight.
+//The operations are run by a pool of workers: the engine is slowed down when they are all busy and the queue is full.
+//The IndexMaps are sent to each peer on its own, a peer that is still busy with an IndexMap only gets the latest one.
+//The other operations toward a peer that already has its maximum in flight are dropped, and so are the pushes
+func (c *ConnectorImpl) Run(ctx context.Context) {
+	for i := 0; i < c.workers; i++ {
+		c.running.Add(1)
+		go c.work(ctx)
+	}
+	c.running.Add(1)
+	go c.dispatchIndexes(ctx)
+	defer func() {
+		close(c.done)
+		c.running.Wait()
+		c.drainJobs()
+	}()
 	for {
 		select {
-		case indexFromChan := <-c.sendIndexCh: // fan out to remote
-			go c.ProcessIndexMap(indexFromChan)
 		case rqFromChan := <-c.RequestKeysCh:
-			if rqFromChan.RequestDestination == c.GetLocalMember().ID() {
+			rq := rqFromChan
+			if rq.RequestDestination == c.GetLocalMember().ID() {
 				// handle the request
-				go c.ProcessDataRequest(rqFromChan)
+				c.submit(ctx, job{run: func() { c.do("ProcessDataRequest", &rq, func() error { return c.ProcessDataRequest(rq) }) }})
+			} else {
+				// forward the query toward the good member
+				hop, ok := c.NextHop(rq.RequestDestination)
+				if !ok {
+					c.report(&ConnectorError{Op: "ForwardDataRequest", Request: &rq, Err: fmt.Errorf("%w: %s", ErrNoRoute, rq.RequestDestination)})
+					continue
+				}
+				c.submitTo(ctx, c.peerSlots(hop), "ForwardDataRequest", &rq, func() error { return c.ForwardDataRequest(hop, rq) })
+			}
+		case rqFromChan := <-c.RequestIndexCh:
+			if rqFromChan.RequestDestination == c.GetLocalMember().ID() {
+				// the engine builds the response
+				c.submit(ctx, job{run: func() {
+					select {
+					case c.ReceiveIndexRequestCh <- rqFromChan:
+					case <-ctx.Done():
+					}
+				}})
+			} else {
+				c.submitTo(ctx, c.peerSlots(rqFromChan.RequestDestination), "ForwardIndexRequest", nil, func() error { return c.ForwardIndexRequest(rqFromChan) })
+			}
+		case rsFromChan := <-c.sendIndexResponseCh:
+			if rsFromChan.RequestSource == c.GetLocalMember().ID() {
+				c.submit(ctx, job{run: func() {
+					select {
+					case c.ReceiveIndexResponseCh <- rsFromChan:
+					case <-ctx.Done():
+					}
+				}})
 			} else {
-				// forward the query to the good member
-				go c.ForwardDataRequest(rqFromChan)
+				// send the response back to the member that issued the request
+				c.submitTo(ctx, c.peerSlots(rsFromChan.RequestSource), "ProcessIndexResponse", nil, func() error { return c.ProcessIndexResponse(rsFromChan) })
 			}
-		case <-stop:
+		case pushFromChan := <-c.pushCh: // fan out to neighbors
+			c.submitTo(ctx, &c.pushes, "ProcessDataPush", nil, func() error { return c.ProcessDataPush(pushFromChan) })
+		case <-ctx.Done():
 			return
 		}
 	}
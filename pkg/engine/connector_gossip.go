@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//GossipStrategy selects the connected peers that receive an IndexMap. It is called once per IndexMap
+type GossipStrategy interface {
+	Targets(peers []ID) []ID
+	//Periods returns the mean number of IndexMaps between two sent to the same peer, out of peers connected
+	Periods(peers int) int
+}
+
+//WithGossip sets how the IndexMaps are spread to the peers. Default is Broadcast. The failure detector of the engine
+//expects the neighbors at the pace of the strategy, so all the members of the mesh must use the same one
+func WithGossip(strategy GossipStrategy) ConnectorOption {
+	return func(c *ConnectorImpl) {
+		c.gossip = strategy
+	}
+}
+
+type broadcast struct{}
+
+//Broadcast sends each IndexMap to all the connected peers
+func Broadcast() GossipStrategy {
+	return broadcast{}
+}
+
+func (broadcast) Targets(peers []ID) []ID {
+	return peers
+}
+
+func (broadcast) Periods(int) int {
+	return 1
+}
+
+//GossipPeriods returns the mean number of sync periods between two IndexMaps sent to the same peer
+func (c *ConnectorImpl) GossipPeriods() int {
+	return c.gossip.Periods(len(c.Peers()))
+}
+
+//PeerSampler maintains a partial view of the connected peers. The view follows the connections and, at each update,
+//one of its peers is swapped for a peer out of the view, so that all the peers are sampled over time
+type PeerSampler struct {
+	sync.Mutex
+	size   int
+	view   []ID
+	random *rand.Rand
+}
+
+//NewPeerSampler returns a sampler whose view holds up to size peers
+func NewPeerSampler(size int) *PeerSampler {
+	if size < 1 {
+		size = 1
+	}
+	return &PeerSampler{size: size, random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+//Update refreshes the view with the connected peers
+func (s *PeerSampler) Update(peers []ID) {
+	s.Lock()
+	defer s.Unlock()
+	connected := map[ID]struct{}{}
+	for _, id := range peers {
+		connected[id] = struct{}{}
+	}
+	view := s.view[:0]
+	for _, id := range s.view {
+		if _, ok := connected[id]; ok {
+			view = append(view, id)
+			delete(connected, id)
+		}
+	}
+	outside := make([]ID, 0, len(connected))
+	for _, id := range peers {
+		if _, ok := connected[id]; ok {
+			outside = append(outside, id)
+		}
+	}
+	s.random.Shuffle(len(outside), func(i, j int) { outside[i], outside[j] = outside[j], outside[i] })
+	for len(view) < s.size && len(outside) > 0 {
+		view = append(view, outside[0])
+		outside = outside[1:]
+	}
+	if len(outside) > 0 && len(view) > 0 {
+		view[s.random.Intn(len(view))] = outside[0] // rotation
+	}
+	s.view = view
+}
+
+//View returns the peers of the view
+func (s *PeerSampler) View() []ID {
+	s.Lock()
+	defer s.Unlock()
+	return append([]ID{}, s.view...)
+}
+
+//Sample returns up to k peers of the view chosen at random
+func (s *PeerSampler) Sample(k int) []ID {
+	s.Lock()
+	defer s.Unlock()
+	sample := []ID{}
+	for _, i := range s.random.Perm(len(s.view)) {
+		if len(sample) == k {
+			break
+		}
+		sample = append(sample, s.view[i])
+	}
+	return sample
+}
+
+type fanout struct {
+	k       int
+	sampler *PeerSampler
+}
+
+//Fanout sends each IndexMap to k peers chosen at random in a partial view of viewSize peers, at least k.
+//Each peer receives an IndexMap about every peers/k sync periods instead of each one. k is at least 1
+func Fanout(k int, viewSize int) GossipStrategy {
+	if k < 1 {
+		k = 1
+	}
+	if viewSize < k {
+		viewSize = k
+	}
+	return &fanout{k: k, sampler: NewPeerSampler(viewSize)}
+}
+
+func (f *fanout) Targets(peers []ID) []ID {
+	f.sampler.Update(peers)
+	return f.sampler.Sample(f.k)
+}
+
+//Periods is peers/k: the rotation gives every peer the same share of the view over time
+func (f *fanout) Periods(peers int) int {
+	if peers <= f.k {
+		return 1
+	}
+	return (peers + f.k - 1) / f.k
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func peerIDs(n int) []ID {
+	ids := make([]ID, n)
+	for i := range ids {
+		ids[i] = ID(fmt.Sprintf("P%d", i))
+	}
+	return ids
+}
+
+func TestPeerSampler(t *testing.T) {
+	peers := peerIDs(10)
+	s := NewPeerSampler(4)
+	seen := map[ID]struct{}{}
+	for i := 0; i < 200; i++ {
+		s.Update(peers)
+		if v := s.View(); len(v) != 4 {
+			t.Fatalf("expected a view of 4 peers, got %v", v)
+		}
+		for _, id := range s.View() {
+			seen[id] = struct{}{}
+		}
+	}
+	if len(seen) != len(peers) {
+		t.Fatalf("the view did not rotate over all the peers: %d seen", len(seen))
+	}
+
+	s.Update(peers[:2])
+	if v := s.View(); len(v) != 2 {
+		t.Fatalf("the view kept disconnected peers: %v", v)
+	}
+	if sample := s.Sample(3); len(sample) != 2 {
+		t.Fatalf("expected the 2 peers of the view, got %v", sample)
+	}
+}
+
+func TestFanout(t *testing.T) {
+	peers := peerIDs(10)
+	f := Fanout(3, 5)
+	for i := 0; i < 20; i++ {
+		targets := f.Targets(peers)
+		distinct := map[ID]struct{}{}
+		for _, id := range targets {
+			distinct[id] = struct{}{}
+		}
+		if len(targets) != 3 || len(distinct) != 3 {
+			t.Fatalf("expected 3 distinct targets, got %v", targets)
+		}
+	}
+	if targets := Broadcast().Targets(peers); len(targets) != len(peers) {
+		t.Fatalf("broadcast did not select all the peers: %v", targets)
+	}
+	if p, b := f.Periods(len(peers)), Broadcast().Periods(len(peers)); p != 4 || b != 1 {
+		t.Fatalf("unexpected periods: fanout %d, broadcast %d", p, b)
+	}
+	// a fanout below 1 still reaches a peer
+	if targets := Fanout(0, 5).Targets(peers); len(targets) != 1 {
+		t.Fatalf("expected 1 target with a fanout of 0, got %v", targets)
+	}
+}
+
+//gossipCore counts the IndexMaps forwarded to a set of peers
+type gossipCore struct {
+	ConnectorCore
+	peers []ID
+	mutex sync.Mutex
+	sent  int
+}
+
+func (c *gossipCore) Peers() []ID {
+	return c.peers
+}
+
+func (c *gossipCore) ForwardIndexMap(id ID, index IndexMap) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sent++
+	return nil
+}
+
+func (c *gossipCore) count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.sent
+}
+
+func TestConnectorGossip(t *testing.T) {
+	core := &gossipCore{peers: peerIDs(10)}
+	c := NewConnector(nil, func(LocalMember, ConnectorChan) ConnectorCore { return core }, WithGossip(Fanout(2, 4)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	for i := 1; i <= 5; i++ {
+		c.SendIndexChan() <- IndexMap{Source: "M0"}
+		waitFor(t, "the IndexMap", func() bool { return core.count() == 2*i })
+	}
+	time.Sleep(5 * syncPeriod)
+	if n := core.count(); n != 10 {
+		t.Fatalf("expected 10 IndexMaps sent, got %d", n)
+	}
+}
+
+func TestGossipFullMesh(t *testing.T) {
+	members := make([]*testMember, 8)
+	engines := make([]*Engine, len(members))
+	for i := range members {
+		members[i] = newTestMember(fmt.Sprintf("M%d", i), NewMapStore(), WithGossip(Fanout(2, 4)))
+		engines[i] = NewEngine(members[i], syncPeriod)
+	}
+	for i := range engines {
+		for j := range members {
+			if j != i {
+				engines[i].AddMember(members[j])
+			}
+		}
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	for i, m := range members[:4] {
+		m.Write(newTestItem(Key(fmt.Sprintf("key%d", i)), "value"))
+	}
+	waitForCount(t, 4, members, checkPeriod, 5*time.Second)
+	for _, m := range members {
+		if n := m.GetStore().(*MapStore).Count(); n != 4 {
+			t.Fatalf("%s has %d items instead of 4", m.ID(), n)
+		}
+	}
+}
+
+func TestGossipFailureFloor(t *testing.T) {
+	m := newTestMember("M0", NewMapStore(), WithGossip(Fanout(2, 4)))
+	e := NewEngine(m, syncPeriod)
+	for _, id := range peerIDs(8) {
+		e.AddMember(newTestMember(string(id), NewMapStore()))
+	}
+	if p := m.connector.GossipPeriods(); p != 4 {
+		t.Fatalf("expected an IndexMap every 4 periods out of 8 peers, got %d", p)
+	}
+	t0 := time.Now()
+	e.failureDetector.heard("P0", t0)
+	if s, _ := e.failureDetector.state("P0", t0.Add(20*syncPeriod)); s != PeerDead {
+		t.Fatalf("expected a peer silent for 20 periods to be dead at the broadcast pace, got %v", s)
+	}
+	e.failureDetector.setPeriods(m.connector.GossipPeriods())
+	if s, _ := e.failureDetector.state("P0", t0.Add(20*syncPeriod)); s != PeerAlive {
+		t.Fatalf("a peer silent for 5 gossip intervals should be alive, got %v", s)
+	}
+}
@@ -0,0 +1,293 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+//DefaultPeerInflight is the number of operations toward a peer that can be queued or running at the same time
+const DefaultPeerInflight = 4
+
+//ErrPeerBusy is reported for an operation dropped because its peer already has its maximum of operations in flight
+var ErrPeerBusy = errors.New("peer busy")
+
+//WithPeerInflight sets the number of operations toward a peer, other than the IndexMaps, that can be queued or
+//running at the same time; the pushes to all the peers share the same limit. A peer that does not answer holds at
+//most that many workers. Default is DefaultPeerInflight
+func WithPeerInflight(n int) ConnectorOption {
+	return func(c *ConnectorImpl) {
+		c.peerInflight = n
+	}
+}
+
+//inflightSlots counts the operations queued or running toward a peer
+type inflightSlots struct {
+	inflight int
+	dropped  uint64
+	sent     uint64
+}
+
+//peerSlots returns the slots of the peer id
+func (c *ConnectorImpl) peerSlots(id ID) *inflightSlots {
+	c.slotsMutex.Lock()
+	defer c.slotsMutex.Unlock()
+	s, ok := c.requests[id]
+	if !ok {
+		s = &inflightSlots{}
+		c.requests[id] = s
+	}
+	return s
+}
+
+//job is an operation queued for a worker. Its slots, if any, are released once it is run or dropped
+type job struct {
+	run   func()
+	slots *inflightSlots
+}
+
+//submitTo queues the operation if a slot is free, else it drops it and reports ErrPeerBusy. The slot is released
+//when the operation completes, or when it is dropped because the connector stops
+func (c *ConnectorImpl) submitTo(ctx context.Context, slots *inflightSlots, op string, rq *DataRequest, f func() error) {
+	if err := c.acquire(slots); err != nil {
+		c.report(&ConnectorError{Op: op, Request: rq, Err: err})
+		return
+	}
+	if !c.submit(ctx, job{run: func() { c.do(op, rq, f) }, slots: slots}) {
+		c.unqueue(slots)
+	}
+}
+
+//acquire takes a slot, it returns ErrPeerBusy if none is free
+func (c *ConnectorImpl) acquire(slots *inflightSlots) error {
+	c.slotsMutex.Lock()
+	defer c.slotsMutex.Unlock()
+	if slots.inflight >= c.peerInflight {
+		slots.dropped++
+		return fmt.Errorf("%d in flight: %w", c.peerInflight, ErrPeerBusy)
+	}
+	slots.inflight++
+	return nil
+}
+
+//release frees the slot of a completed operation
+func (c *ConnectorImpl) release(slots *inflightSlots) {
+	c.slotsMutex.Lock()
+	defer c.slotsMutex.Unlock()
+	slots.inflight--
+	slots.sent++
+}
+
+//unqueue frees the slot of an operation that was never run, it counts as dropped
+func (c *ConnectorImpl) unqueue(slots *inflightSlots) {
+	c.slotsMutex.Lock()
+	defer c.slotsMutex.Unlock()
+	slots.inflight--
+	slots.dropped++
+}
+
+//submit queues the operation for a worker. It blocks while the queue is full, until ctx is done. It returns false
+//if the operation was not queued
+func (c *ConnectorImpl) submit(ctx context.Context, j job) bool {
+	select {
+	case c.jobs <- j:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+//work runs the queued operations until ctx is done, the operations still queued are dropped by drainJobs
+func (c *ConnectorImpl) work(ctx context.Context) {
+	defer c.running.Done()
+	for {
+		select {
+		case j := <-c.jobs:
+			atomic.AddInt64(&c.busy, 1)
+			j.run()
+			atomic.AddInt64(&c.busy, -1)
+			if j.slots != nil {
+				c.release(j.slots)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//drainJobs drops the operations left in the queue once the workers are stopped and frees their slots
+func (c *ConnectorImpl) drainJobs() {
+	for {
+		select {
+		case j := <-c.jobs:
+			if j.slots != nil {
+				c.unqueue(j.slots)
+			}
+		default:
+			return
+		}
+	}
+}
+
+//peerQueue holds the IndexMap waiting to be sent to a peer. Only the latest IndexMap matters: a newer one replaces it
+type peerQueue struct {
+	sync.Mutex
+	pending *IndexMap
+	sending bool
+	dropped uint64
+	sent    uint64
+}
+
+//dispatchIndexes queues the IndexMaps of the engine for each peer. It never waits for a peer
+func (c *ConnectorImpl) dispatchIndexes(ctx context.Context) {
+	defer c.running.Done()
+	for {
+		select {
+		case index := <-c.sendIndexCh:
+			c.queueIndex(ctx, index)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//queueIndex replaces the IndexMap waiting for each peer selected by the gossip strategy and starts a sender for
+//the peers that are idle
+func (c *ConnectorImpl) queueIndex(ctx context.Context, index IndexMap) {
+	connected := c.Peers()
+	peers := map[ID]struct{}{}
+	for _, id := range connected {
+		peers[id] = struct{}{}
+	}
+	c.peersMutex.Lock()
+	defer c.peersMutex.Unlock()
+	for id, q := range c.peers {
+		if _, ok := peers[id]; !ok {
+			q.Lock()
+			if !q.sending {
+				delete(c.peers, id) // disconnected
+			}
+			q.Unlock()
+		}
+	}
+	for _, id := range c.gossip.Targets(connected) {
+		q, ok := c.peers[id]
+		if !ok {
+			q = &peerQueue{}
+			c.peers[id] = q
+		}
+		q.Lock()
+		if q.pending != nil {
+			q.dropped++
+		}
+		im := index
+		q.pending = &im
+		if !q.sending {
+			q.sending = true
+			c.running.Add(1)
+			go c.sendIndexes(ctx, id, q)
+		}
+		q.Unlock()
+	}
+}
+
+//sendIndexes sends the IndexMaps queued for the peer id until its queue is empty
+func (c *ConnectorImpl) sendIndexes(ctx context.Context, id ID, q *peerQueue) {
+	defer c.running.Done()
+	for {
+		q.Lock()
+		index := q.pending
+		q.pending = nil
+		if index == nil || ctx.Err() != nil {
+			q.sending = false
+			q.Unlock()
+			return
+		}
+		q.Unlock()
+		c.do("ForwardIndexMap", nil, func() error { return c.ForwardIndexMap(id, *index) })
+		atomic.AddUint64(&q.sent, 1)
+	}
+}
+
+//QueueMetrics describes a queue of the connector
+type QueueMetrics struct {
+	//Depth is the number of messages waiting
+	Depth int
+	//Capacity of the queue
+	Capacity int
+	//Dropped messages, superseded by a newer one or over the capacity
+	Dropped uint64
+	//Sent messages
+	Sent uint64
+}
+
+//ConnectorMetrics is a snapshot of the queues of the connector
+type ConnectorMetrics struct {
+	//Channels between the engine and the connector, by name
+	Channels map[string]QueueMetrics
+	//Jobs are the operations waiting for a worker
+	Jobs QueueMetrics
+	//Workers is the size of the pool, BusyWorkers the ones running an operation
+	Workers     int
+	BusyWorkers int
+	//Peers are the IndexMaps waiting to be sent to each peer
+	Peers map[ID]QueueMetrics
+	//Requests are the other operations queued or running toward each peer, Pushes the pushes to all the peers
+	Requests map[ID]QueueMetrics
+	Pushes   QueueMetrics
+}
+
+func chanMetrics(depth, capacity int) QueueMetrics {
+	return QueueMetrics{Depth: depth, Capacity: capacity}
+}
+
+//Metrics returns the depth of the queues of the connector
+func (c *ConnectorImpl) Metrics() ConnectorMetrics {
+	m := ConnectorMetrics{
+		Channels: map[string]QueueMetrics{
+			"ReceiveIndex":         chanMetrics(len(c.ReceiveIndexCh), cap(c.ReceiveIndexCh)),
+			"SendIndex":            chanMetrics(len(c.sendIndexCh), cap(c.sendIndexCh)),
+			"RequestKeys":          chanMetrics(len(c.RequestKeysCh), cap(c.RequestKeysCh)),
+			"ReceiveData":          chanMetrics(len(c.ReceiveDataCh), cap(c.ReceiveDataCh)),
+			"RequestIndex":         chanMetrics(len(c.RequestIndexCh), cap(c.RequestIndexCh)),
+			"ReceiveIndexRequest":  chanMetrics(len(c.ReceiveIndexRequestCh), cap(c.ReceiveIndexRequestCh)),
+			"SendIndexResponse":    chanMetrics(len(c.sendIndexResponseCh), cap(c.sendIndexResponseCh)),
+			"ReceiveIndexResponse": chanMetrics(len(c.ReceiveIndexResponseCh), cap(c.ReceiveIndexResponseCh)),
+			"Push":                 chanMetrics(len(c.pushCh), cap(c.pushCh)),
+			"ReceivePush":          chanMetrics(len(c.ReceivePushCh), cap(c.ReceivePushCh)),
+			"Error":                chanMetrics(len(c.errorCh), cap(c.errorCh)),
+		},
+		Jobs:        chanMetrics(len(c.jobs), cap(c.jobs)),
+		Workers:     c.workers,
+		BusyWorkers: int(atomic.LoadInt64(&c.busy)),
+		Peers:       map[ID]QueueMetrics{},
+		Requests:    map[ID]QueueMetrics{},
+	}
+	c.slotsMutex.Lock()
+	for id, s := range c.requests {
+		m.Requests[id] = s.metrics(c.peerInflight)
+	}
+	m.Pushes = c.pushes.metrics(c.peerInflight)
+	errs := m.Channels["Error"]
+	errs.Dropped = atomic.LoadUint64(&c.droppedErrors)
+	m.Channels["Error"] = errs
+	c.slotsMutex.Unlock()
+	c.peersMutex.Lock()
+	defer c.peersMutex.Unlock()
+	for id, q := range c.peers {
+		q.Lock()
+		qm := QueueMetrics{Capacity: 1, Dropped: q.dropped, Sent: atomic.LoadUint64(&q.sent)}
+		if q.pending != nil {
+			qm.Depth = 1
+		}
+		q.Unlock()
+		m.Peers[id] = qm
+	}
+	return m
+}
+
+func (s *inflightSlots) metrics(capacity int) QueueMetrics {
+	return QueueMetrics{Depth: s.inflight, Capacity: capacity, Dropped: s.dropped, Sent: s.sent}
+}
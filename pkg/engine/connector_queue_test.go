@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+//stubCore records the IndexMaps and DataRequests forwarded to each peer. The calls toward the peer "slow" and the
+//pushes wait for release
+type stubCore struct {
+	ConnectorCore
+	local     LocalMember
+	entered   chan struct{}
+	release   chan struct{}
+	mutex     sync.Mutex
+	received  map[ID][]IndexMap
+	requested map[ID][]DataRequest
+}
+
+func newStubConnector(options ...ConnectorOption) (*ConnectorImpl, *stubCore) {
+	core := &stubCore{
+		entered:   make(chan struct{}, 100),
+		release:   make(chan struct{}),
+		received:  map[ID][]IndexMap{},
+		requested: map[ID][]DataRequest{},
+		local:     newTestMember("local", NewMapStore()),
+	}
+	c := NewConnector(nil, func(LocalMember, ConnectorChan) ConnectorCore { return core }, options...)
+	return c, core
+}
+
+func (c *stubCore) Peers() []ID {
+	return []ID{"fast", "slow"}
+}
+
+func (c *stubCore) ForwardIndexMap(id ID, index IndexMap) error {
+	if id == "slow" {
+		c.entered <- struct{}{}
+		<-c.release
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.received[id] = append(c.received[id], index)
+	return nil
+}
+
+func (c *stubCore) GetLocalMember() LocalMember {
+	return c.local
+}
+
+func (c *stubCore) ForwardDataRequest(hop ID, rq DataRequest) error {
+	if hop == "slow" {
+		c.entered <- struct{}{}
+		<-c.release
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.requested[hop] = append(c.requested[hop], rq)
+	return nil
+}
+
+func (c *stubCore) ProcessDataPush(p DataPush) error {
+	c.entered <- struct{}{}
+	<-c.release
+	return nil
+}
+
+func (c *stubCore) count(id ID) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.received[id])
+}
+
+func (c *stubCore) last(id ID) ID {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if l := len(c.received[id]); l > 0 {
+		return c.received[id][l-1].Source
+	}
+	return ""
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(checkPeriod)
+	}
+}
+
+func TestConnectorSlowPeer(t *testing.T) {
+	c, core := newStubConnector()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	c.SendIndexChan() <- IndexMap{Source: "0"}
+	<-core.entered
+	for i := 1; i < 10; i++ {
+		c.SendIndexChan() <- IndexMap{Source: ID(fmt.Sprint(i))}
+	}
+	// the fast peer is not held back by the slow one
+	waitFor(t, "the fast peer", func() bool { return core.last("fast") == "9" })
+	waitFor(t, "the queue of the slow peer", func() bool { return c.Metrics().Peers["slow"].Dropped == 8 })
+	if m := c.Metrics().Peers["slow"]; m.Depth != 1 || m.Sent != 0 {
+		t.Fatalf("unexpected metrics of the slow peer %+v", m)
+	}
+
+	close(core.release)
+	waitFor(t, "the slow peer", func() bool { return core.count("slow") == 2 })
+	core.mutex.Lock()
+	defer core.mutex.Unlock()
+	if s := core.received["slow"]; s[0].Source != "0" || s[1].Source != "9" {
+		t.Fatalf("the slow peer did not get the latest IndexMap: %s then %s", s[0].Source, s[1].Source)
+	}
+}
+
+func TestConnectorWorkers(t *testing.T) {
+	c, core := newStubConnector(WithConnectorWorkers(2), WithConnectorQueueSize(4), WithPeerInflight(5))
+	ctx, cancel := context.WithCancel(context.Background())
+	go c.Run(ctx)
+
+	for i := 0; i < 5; i++ {
+		c.PushChan() <- DataPush{}
+	}
+	<-core.entered
+	<-core.entered
+	waitFor(t, "the queued pushes", func() bool { return c.Metrics().Jobs.Depth == 3 })
+	if m := c.Metrics(); m.BusyWorkers != 2 || m.Workers != 2 || m.Jobs.Capacity != 4 {
+		t.Fatalf("unexpected metrics %+v", m)
+	}
+	select {
+	case <-core.entered:
+		t.Fatalf("more operations than workers are running")
+	case <-time.After(5 * syncPeriod):
+	}
+
+	cancel()
+	close(core.release)
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("the connector did not stop")
+	}
+}
+
+func TestConnectorPeerInflight(t *testing.T) {
+	c, core := newStubConnector(WithPeerInflight(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	for i := 0; i < 5; i++ {
+		c.RequestKeysChan() <- DataRequest{RequestDestination: "slow", KeyIDPairs: KeyIDPairs{{ID: "slow", Key: Key(fmt.Sprint(i))}}}
+	}
+	<-core.entered
+	<-core.entered
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-c.ErrorChan():
+			var cerr *ConnectorError
+			if !errors.As(err, &cerr) || !errors.Is(err, ErrPeerBusy) || cerr.Request == nil {
+				t.Fatalf("expected ErrPeerBusy with the request, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("the requests over the limit were not reported")
+		}
+	}
+	// the slow peer holds 2 workers, the fast one is still served
+	c.RequestKeysChan() <- DataRequest{RequestDestination: "fast"}
+	waitFor(t, "the fast peer", func() bool {
+		core.mutex.Lock()
+		defer core.mutex.Unlock()
+		return len(core.requested["fast"]) == 1
+	})
+	if m := c.Metrics().Requests["slow"]; m.Depth != 2 || m.Capacity != 2 || m.Dropped != 3 || m.Sent != 0 {
+		t.Fatalf("unexpected metrics of the slow peer %+v", m)
+	}
+
+	close(core.release)
+	waitFor(t, "the slow peer", func() bool { m := c.Metrics().Requests["slow"]; return m.Depth == 0 && m.Sent == 2 })
+}
+
+func TestConnectorErrorsDropped(t *testing.T) {
+	c, core := newStubConnector(WithPeerInflight(1), WithConnectorQueueSize(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	// nobody reads the errors: the ones over the capacity of the channel are dropped instead of blocking Run
+	for i := 0; i < 4; i++ {
+		c.RequestKeysChan() <- DataRequest{RequestDestination: "slow"}
+	}
+	<-core.entered
+	waitFor(t, "the dropped errors", func() bool { return c.Metrics().Channels["Error"].Dropped == 2 })
+	c.RequestKeysChan() <- DataRequest{RequestDestination: "fast"}
+	waitFor(t, "the fast peer", func() bool {
+		core.mutex.Lock()
+		defer core.mutex.Unlock()
+		return len(core.requested["fast"]) == 1
+	})
+	close(core.release)
+}
+
+func TestConnectorStopReleasesSlots(t *testing.T) {
+	c, core := newStubConnector(WithConnectorWorkers(1), WithConnectorQueueSize(1), WithPeerInflight(5))
+	ctx, cancel := context.WithCancel(context.Background())
+	go c.Run(ctx)
+
+	// one push runs, one is queued and Run waits to queue the last one
+	for i := 0; i < 3; i++ {
+		c.PushChan() <- DataPush{}
+	}
+	<-core.entered
+	waitFor(t, "the queued pushes", func() bool {
+		m := c.Metrics()
+		return m.Jobs.Depth == 1 && m.Channels["Push"].Depth == 0 && m.Pushes.Depth == 3
+	})
+
+	cancel()
+	close(core.release)
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("the connector did not stop")
+	}
+	// the pushes never run give their slot back
+	waitFor(t, "the released slots", func() bool { m := c.Metrics().Pushes; return m.Depth == 0 && m.Sent+m.Dropped == 3 })
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+)
+
+//MaxRouteHops bounds the number of members a DataRequest can cross, so that a request caught in a routing loop
+//is given up
+const MaxRouteHops = 16
+
+//SetRouter sets the routes used to reach the members that are not neighbors. Without router a DataRequest is sent
+//to its destination directly, with a router it fails with ErrNoRoute when no route leads to its destination
+func (c *ConnectorImpl) SetRouter(r Router) {
+	c.routerMutex.Lock()
+	defer c.routerMutex.Unlock()
+	c.router = r
+}
+
+//NextHop returns the neighbor to which a message for destination is sent, false if the router has no route toward it
+func (c *ConnectorImpl) NextHop(destination ID) (ID, bool) {
+	c.routerMutex.RLock()
+	r := c.router
+	c.routerMutex.RUnlock()
+	if r == nil {
+		return destination, true
+	}
+	return r.NextHop(destination)
+}
+
+//ServeDataRequest returns the items of rq received from a neighbor. The local member serves them if it is the
+//RequestDestination, else rq is relayed to the next hop with relay and the items come back the same way.
+//ctx is the one of the incoming request: the relay is abandoned with it. A relay takes a slot of the next hop like
+//the requests of the local member, it fails with ErrPeerBusy if the next hop has its maximum in flight
+func (c *ConnectorImpl) ServeDataRequest(ctx context.Context, rq DataRequest, relay func(ctx context.Context, hop ID, rq DataRequest) (Items, error)) (Items, error) {
+	if rq.RequestDestination == "" || rq.RequestDestination == c.GetLocalMember().ID() {
+		return c.GetLocalMember().GetData(rq.KeyIDPairs), nil
+	}
+	if rq.Hops >= MaxRouteHops {
+		return nil, fmt.Errorf("%w: %s after %d hops", ErrNoRoute, rq.RequestDestination, rq.Hops)
+	}
+	hop, ok := c.NextHop(rq.RequestDestination)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoRoute, rq.RequestDestination)
+	}
+	rq.Hops++
+	slots := c.peerSlots(hop)
+	if err := c.acquire(slots); err != nil {
+		return nil, fmt.Errorf("relay to %s: %w", hop, err)
+	}
+	defer c.release(slots)
+	return relay(ctx, hop, rq)
+}
@@ -1,8 +1,10 @@
 package engine
 
 import (
+	"context"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,6 +15,68 @@ type Engine struct {
 	indexTimeCache       map[ID]time.Time
 	lastLocalKeys        []StampedKey
 	syncPeriod           time.Duration
+	scheduler            *syncScheduler
+	indexMode            IndexMode
+	merkleTreesMutex     sync.RWMutex
+	merkleTrees          map[ID][]builtMerkleTree
+	indexHistoriesMutex  sync.RWMutex
+	indexHistories       map[ID]*indexHistory
+	pushHops             int
+	pushSequence         uint64
+	pushSeen             *pushSeenSet
+	droppedPushes        uint64
+	droppedExchanges     uint64
+	started              int32         // set by the first Run
+	stopped              chan struct{} // closed when the engine stops
+	retry                RetryPolicy
+	errorHandler         func(error)
+	membersMutex         sync.Mutex
+	members              map[ID]Member
+	disconnected         map[ID]chan struct{}
+	departed             map[ID]time.Time
+	failureDetector      *failureDetector
+	retiredMutex         sync.RWMutex
+	retired              map[ID]tombstone
+	ownerExpiry          time.Duration
+	ownerProgress        map[ID]ownerProgress
+	clock                Clock
+	recovery             *recovery
+	watchersMutex        sync.RWMutex
+	watchers             map[*Watcher]struct{}
+	interestsMutex       sync.RWMutex
+	interest             MemberInterest
+	neighborInterests    map[ID]map[ID]MemberInterest
+	knownInterests       map[ID]MemberInterest
+	neighborScopes       map[ID][]Interest
+	indexTimeFloor       map[ID]time.Time
+	handoversMutex       sync.Mutex
+	handovers            map[handoverID]handoverState
+	pendingKeys          map[Key]pendingKey
+	digestsMutex         sync.RWMutex
+	peerDigests          map[ID]Digests
+	barriersMutex        sync.Mutex
+	barriers             map[*Barrier]struct{}
+	fetchesMutex         sync.Mutex
+	fetches              map[KeyIDPair]*inflightFetch
+	fetchedVersions      map[ID]fetchedVersion
+	fetchTimeout         time.Duration
+	duplicateFetches     uint64
+	routesMutex          sync.RWMutex
+	routes               map[ID]route
+	localIndexCache      localIndexCache
+	coalescedIndexMaps   uint64
+	shutdownTimeout      time.Duration
+	finalPush            bool
+}
+
+//Option configures an Engine
+type Option func(*Engine)
+
+//WithIndexMode sets how the engine describes its indexes to the other members. Default is IndexModeFull
+func WithIndexMode(mode IndexMode) Option {
+	return func(e *Engine) {
+		e.indexMode = mode
+	}
 }
 
 func (e *Engine) updateIndexTime(id ID, time time.Time) {
@@ -27,55 +91,163 @@ func (e *Engine) getIndexTime(id ID) (time.Time, bool) {
 	return t, ok
 }
 
-func NewEngine(local LocalMember, syncPeriod time.Duration) *Engine {
-	return &Engine{
+//indexTimes returns a copy of the BuildTime of each owner
+func (e *Engine) indexTimes() map[ID]time.Time {
+	e.indexTimeCacheMutext.RLock()
+	defer e.indexTimeCacheMutext.RUnlock()
+	times := make(map[ID]time.Time, len(e.indexTimeCache))
+	for id, t := range e.indexTimeCache {
+		times[id] = t
+	}
+	return times
+}
+
+func NewEngine(local LocalMember, syncPeriod time.Duration, options ...Option) *Engine {
+	e := &Engine{
 		local:          local,
 		indexTimeCache: map[ID]time.Time{},
 		connector:      local.GetConnector(),
 		syncPeriod:     syncPeriod,
+		scheduler:      newSyncScheduler(syncPeriod, syncPeriod, syncPeriod),
+		merkleTrees:    map[ID][]builtMerkleTree{},
+		indexHistories: map[ID]*indexHistory{},
+		// sequences start from the clock so that they stay unique across restarts
+		pushSequence: uint64(time.Now().UnixNano()),
+		pushSeen:     newPushSeenSet(),
+		stopped:      make(chan struct{}),
+		retry:        DefaultRetryPolicy,
+		members:      map[ID]Member{},
+		disconnected: map[ID]chan struct{}{},
+		departed:     map[ID]time.Time{},
+
+		failureDetector: newFailureDetector(syncPeriod),
+		retired:         map[ID]tombstone{},
+		ownerProgress:   map[ID]ownerProgress{},
+		clock:           local.Clock(),
+		recovery:        newRecovery(),
+		watchers:        map[*Watcher]struct{}{},
+
+		neighborInterests: map[ID]map[ID]MemberInterest{},
+		knownInterests:    map[ID]MemberInterest{},
+		neighborScopes:    map[ID][]Interest{},
+		indexTimeFloor:    map[ID]time.Time{},
+		handovers:         map[handoverID]handoverState{},
+		pendingKeys:       map[Key]pendingKey{},
+		peerDigests:       map[ID]Digests{},
+		barriers:          map[*Barrier]struct{}{},
+		fetches:           map[KeyIDPair]*inflightFetch{},
+		fetchedVersions:   map[ID]fetchedVersion{},
+		routes:            map[ID]route{},
+		fetchTimeout:      DefaultFetchTimeoutPeriods * syncPeriod,
+		shutdownTimeout:   DefaultShutdownTimeout,
+	}
+	e.errorHandler = e.logError
+	for _, option := range options {
+		option(e)
+	}
+	e.interest = MemberInterest{Interest: e.interest.Interest, Version: e.clock.Now(), Via: local.ID()}
+	e.connector.SetRouter(e)
+	return e
+}
+
+func (e *Engine) AddMember(p Member) error {
+	if err := e.connector.Connect(p); err != nil {
+		return err
 	}
+	e.membersMutex.Lock()
+	defer e.membersMutex.Unlock()
+	e.members[p.ID()] = p
+	delete(e.departed, p.ID())
+	e.failureDetector.watch(p.ID(), time.Now())
+	return nil
 }
 
-func (e *Engine) AddMember(p Member) {
-	e.connector.Connect(p)
+//RemoveMember stops the exchanges with the member id. The DataRequests waiting for a retry toward it are dropped,
+//their keys are requested to the other neighbors that advertised them
+func (e *Engine) RemoveMember(id ID) error {
+	e.membersMutex.Lock()
+	delete(e.members, id)
+	if c, ok := e.disconnected[id]; ok {
+		close(c)
+		delete(e.disconnected, id)
+	}
+	e.membersMutex.Unlock()
+	e.failureDetector.forget(id)
+	e.forgetInterests(id)
+	e.forgetDigests(id)
+	e.failBarriers(id)
+	e.forgetRoutes(id)
+	e.failFetches(DataRequest{RequestDestination: id})
+	return e.connector.Disconnect(id)
+}
+
+//disconnectedChan returns a channel closed when the member id is removed
+func (e *Engine) disconnectedChan(id ID) <-chan struct{} {
+	e.membersMutex.Lock()
+	defer e.membersMutex.Unlock()
+	c, ok := e.disconnected[id]
+	if !ok {
+		c = make(chan struct{})
+		e.disconnected[id] = c
+	}
+	return c
 }
 
-func (e *Engine) Run(stop <-chan struct{}) {
+//Run synchronizes the local member with the mesh until ctx is done. The loops of the engine are then stopped, the
+//last IndexMap is sent if WithFinalPush is set, and the operations of the connector in flight are drained.
+//It returns the error of the final push, or ErrShutdownTimeout if the shutdown did not complete in time.
+//An Engine runs once, Run returns ErrAlreadyRun when it is called again
+func (e *Engine) Run(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&e.started, 0, 1) {
+		return ErrAlreadyRun
+	}
 	var wg sync.WaitGroup
+	stop := ctx.Done()
+	e.startRecovery(time.Now())
+	e.failureDetector.setPeriods(e.connector.GossipPeriods())
+
+	// the connector outlives the loops: their last sends are still consumed
+	connectorCtx, cancelConnector := context.WithCancel(context.Background())
+	defer cancelConnector()
+	connectorDone := make(chan struct{})
+	go func() {
+		defer close(connectorDone)
+		e.connector.Run(connectorCtx)
+	}()
 
 	wg.Add(1)
+	//Connector errors
 	go func() {
 		defer wg.Done()
-		e.connector.Run(stop)
+		for {
+			select {
+			case err := <-e.connector.ErrorChan():
+				e.handleError(stop, &wg, err)
+			case <-stop:
+				return
+			}
+		}
 	}()
 
 	wg.Add(1)
 	//Synch out Indexes
 	go func() {
 		defer wg.Done()
-		ticker := time.NewTicker(e.syncPeriod)
-		defer ticker.Stop()
+		timer := time.NewTimer(e.scheduler.period())
+		defer timer.Stop()
 		for {
 			select {
-			case <-ticker.C:
-				updatedIndexes := e.local.GetIndexes()
-				for id, index := range updatedIndexes.Indexes {
-					//Set index build time
-					if id != e.local.ID() {
-						index.BuildTime, _ = e.getIndexTime(id)
-					} else {
-						sort.Sort(updatedIndexes.Indexes[id].StampedKeys)
-						if updatedIndexes.Indexes[id].StampedKeys.Equal(e.lastLocalKeys) { // To investigate why /*reflect.DeepEqual(e.lastLocalKeys, updatedIndexes.Indexes[id].StampedKeys)*/ does not work here
-							index.BuildTime, _ = e.getIndexTime(id)
-						} else {
-							index.BuildTime = time.Now()
-							e.updateIndexTime(id, index.BuildTime)
-							e.lastLocalKeys = updatedIndexes.Indexes[id].StampedKeys
-						}
-					}
-					updatedIndexes.Indexes[id] = index
+			case <-timer.C:
+				e.failureDetector.setPeriods(e.connector.GossipPeriods())
+				e.reissueFetches(time.Now())
+				select {
+				case e.connector.SendIndexChan() <- e.buildIndexMap():
+				case <-stop:
+					return
 				}
-				e.connector.SendIndexChan() <- updatedIndexes
+				resetTimer(timer, e.scheduler.next())
+			case <-e.scheduler.wake:
+				resetTimer(timer, e.scheduler.next())
 			case <-stop:
 				return
 			}
@@ -86,14 +258,19 @@ func (e *Engine) Run(stop <-chan struct{}) {
 	//Synch in Indexes
 	go func() {
 		defer wg.Done()
-		ticker := time.NewTicker(20 * time.Second)
-		defer ticker.Stop()
 		for {
 			select {
 			case indexes := <-e.connector.ReceiveIndexChan():
-				e.CheckAndGetUpdates(indexes)
-			case <-ticker.C:
-				//to refresh receive chan in case it is needed
+				for _, latest := range e.coalesceIndexMaps(indexes) {
+					e.CheckAndGetUpdates(latest)
+				}
+			case rq := <-e.connector.ReceiveIndexRequestChan():
+				e.failureDetector.heard(rq.RequestSource, time.Now())
+				e.answerIndexRequest(rq)
+			case rs := <-e.connector.ReceiveIndexResponseChan():
+				e.failureDetector.heard(rs.Source, time.Now())
+				e.processIndexResponse(rs)
+				e.checkRecovery(time.Now())
 			case <-stop:
 				return
 			}
@@ -107,16 +284,80 @@ func (e *Engine) Run(stop <-chan struct{}) {
 		for {
 			select {
 			case dataresponse := <-e.connector.ReceiveDataChan():
-				e.local.Put(dataresponse.Items)
-				for id, t := range dataresponse.AssociatedBuildTime {
-					e.updateIndexTime(id, t)
+				e.receiveData(dataresponse)
+			case p := <-e.connector.ReceivePushChan():
+				if !p.Leave {
+					e.failureDetector.heard(p.Sender, time.Now())
 				}
+				e.observeItems(p.Items)
+				e.scheduler.changed()
+				e.processPush(p)
+			case items := <-e.connector.ReceiveWrittenChan():
+				e.Push(items)
 			case <-stop:
 				return
 			}
 		}
 	}()
-	wg.Wait()
+	<-stop
+	close(e.stopped)
+	return e.shutdown(&wg, cancelConnector, connectorDone)
+}
+
+//buildIndexMap returns the IndexMap advertised to the neighbors
+func (e *Engine) buildIndexMap() IndexMap {
+	// read the clock before the index: an item missing from the index is older than the BuildTime
+	// only if it was removed, items written meanwhile are newer
+	e.checkRecovery(time.Now())
+	now := e.clock.Now()
+	// and the BuildTimes before the keys: a BuildTime recorded meanwhile would be advertised with the keys it misses
+	buildTimes := e.indexTimes()
+	e.invalidateLocalIndexes() // the local member writes its own items without the engine
+	updatedIndexes := e.local.GetIndexes()
+	e.expireItems(updatedIndexes, now)
+	if own, ok := updatedIndexes.Indexes[e.local.ID()]; ok || !e.Recovering() {
+		e.requestPendingKeys(&own)
+		if len(own.StampedKeys) > 0 {
+			updatedIndexes.Indexes[e.local.ID()] = own
+		}
+	}
+	if e.Recovering() {
+		delete(updatedIndexes.Indexes, e.local.ID()) // not published until it is restored
+	}
+	for id, index := range updatedIndexes.Indexes {
+		//Set index build time
+		if id != e.local.ID() {
+			index.BuildTime = buildTimes[id]
+		} else {
+			sort.Sort(updatedIndexes.Indexes[id].StampedKeys)
+			lastBuildTime, _ := e.getIndexTime(id)
+			refresh := e.ownerExpiry > 0 && now.Sub(lastBuildTime) > e.ownerExpiry/4 // show progress to the members expiring owners
+			if updatedIndexes.Indexes[id].StampedKeys.Equal(e.lastLocalKeys) && !refresh { // To investigate why /*reflect.DeepEqual(e.lastLocalKeys, updatedIndexes.Indexes[id].StampedKeys)*/ does not work here
+				index.BuildTime = lastBuildTime
+			} else {
+				index.BuildTime = now
+				e.updateIndexTime(id, index.BuildTime)
+				e.lastLocalKeys = updatedIndexes.Indexes[id].StampedKeys
+				e.scheduler.changed()
+			}
+		}
+		updatedIndexes.Indexes[id] = index
+	}
+	e.expireOwners(updatedIndexes, time.Now())
+	digests := computeDigests(updatedIndexes)
+	switch e.indexMode {
+	case IndexModeMerkle:
+		updatedIndexes = e.summarizeIndexes(updatedIndexes)
+	case IndexModeDelta:
+		updatedIndexes = e.recordIndexes(updatedIndexes)
+	}
+	updatedIndexes = e.pruneIndexes(updatedIndexes)
+	updatedIndexes = addDigests(updatedIndexes, digests)
+	updatedIndexes = e.addTombstones(updatedIndexes)
+	updatedIndexes = e.addHandovers(updatedIndexes, time.Now())
+	updatedIndexes.Clock = now
+	updatedIndexes.Interests = e.advertisedInterests()
+	return updatedIndexes
 }
 
 type keyPair struct {
@@ -129,16 +370,33 @@ func (e *Engine) GetLocalMember() Member {
 }
 
 func (e *Engine) CheckAndGetUpdates(indexMap IndexMap) {
+	for _, h := range indexMap.Handovers {
+		e.applyHandover(h, indexMap.Source)
+	}
+	e.heardInterests(indexMap)
+	e.heardDigests(indexMap)
+	e.heardBarriers(indexMap)
+	e.heardRoutes(indexMap)
+	needs := e.needs(indexMap.Source)
+	now := e.clock.Now()
+	// only the owners of the update are compared: absence of proof is not a proof of absence
 	membersID := map[ID]struct{}{}
-	currentIndexes := e.local.GetIndexes().Indexes
+	currentIndexes := e.localIndexes()
 	updateIndexes := indexMap.Indexes
 	for id := range updateIndexes {
 		membersID[id] = struct{}{}
 	}
-	for id := range currentIndexes {
-		membersID[id] = struct{}{}
+	if !e.syncsOwner(e.local.ID()) {
+		delete(membersID, e.local.ID())
+	}
+	e.recoveryHeard(indexMap)
+	indexRequest := IndexRequest{
+		RequestSource:      e.local.ID(),
+		RequestDestination: indexMap.Source,
+		MerkleRoots:        map[ID]Hash{},
+		MerkleNodes:        map[ID][]MerkleNodeID{},
+		Since:              map[ID]time.Time{},
 	}
-	delete(membersID, e.local.ID())
 	for id := range membersID {
 		currentIndex, ok := currentIndexes[id]
 		if !ok {
@@ -152,49 +410,135 @@ func (e *Engine) CheckAndGetUpdates(indexMap IndexMap) {
 		}
 
 		previous, _ := e.getIndexTime(id)
+		if updateIndex.Retired {
+			// a retirement of the local member is not a version to recover
+			if id != e.local.ID() && !updateIndex.BuildTime.Before(previous) {
+				e.retireOwner(id, updateIndex.BuildTime)
+			}
+			continue
+		}
 		//Check if we already have the latest version
-		if !previous.Before(updateIndex.BuildTime) {
+		if !previous.Before(updateIndex.BuildTime) || e.isStale(id, updateIndex.BuildTime) {
 			continue // we have a better version
 		}
+		e.unretire(id) // the owner is back with a newer version
+		e.scheduler.changed()
 
-		//index all keys and pair them
-		allKeys := map[Key]keyPair{}
-		for i, k := range currentIndex.StampedKeys {
-			allKeys[k.Key] = keyPair{current: &currentIndex.StampedKeys[i]}
-		}
-		for i, k := range updateIndex.StampedKeys {
-			kp, ok := allKeys[k.Key]
-			if !ok {
-				kp = keyPair{update: &updateIndex.StampedKeys[i]}
+		switch updateIndex.Mode {
+		case IndexModeMerkle:
+			if e.localMerkleTree(id, updateIndex.MerkleDepth).Root() == updateIndex.MerkleRoot {
+				e.updateIndexTime(id, updateIndex.BuildTime)
 			} else {
-				kp.update = &updateIndex.StampedKeys[i]
+				// descend from the root into the differing subtrees
+				indexRequest.MerkleRoots[id] = updateIndex.MerkleRoot
+				indexRequest.MerkleNodes[id] = []MerkleNodeID{0}
 			}
-			allKeys[k.Key] = kp
+			continue
+		case IndexModeDelta:
+			indexRequest.Since[id] = previous
+			continue
 		}
-		toFetch := KeyIDPairs{}
-		toDelete := KeyIDPairs{}
 
-		//compare key version
-		for k, kp := range allKeys {
-			if kp.current == nil {
-				toFetch = append(toFetch, KeyIDPair{ID: id, Key: k})
-				continue
-			}
-			if kp.update == nil {
-				toDelete = append(toDelete, KeyIDPair{ID: id, Key: k})
-				continue
-			}
-			if kp.update.Timestamp.After(kp.current.Timestamp) {
-				toFetch = append(toFetch, KeyIDPair{ID: id, Key: k})
+		toFetch, toDelete := diffKeys(id, currentIndex.StampedKeys, updateIndex.StampedKeys.unexpired(now), updateIndex.BuildTime)
+		toFetch = toFetch.filter(needs)
+		if indexMap.Scope != nil {
+			// the keys out of the scope were pruned from the index, they are not deleted
+			toDelete = toDelete.filter(func(kp KeyIDPair) bool { return matchAny(indexMap.Scope, kp) })
+		}
+		if len(toFetch) == 0 && len(toDelete) == 0 {
+			e.updateIndexTime(id, updateIndex.BuildTime)
+			continue
+		}
+		e.applyUpdates(indexMap.Source, id, updateIndex.BuildTime, toFetch, toDelete, updateIndex.StampedKeys)
+	}
+	if len(indexRequest.MerkleNodes) > 0 || len(indexRequest.Since) > 0 {
+		e.requestIndex(indexRequest)
+	}
+	e.checkRecovery(time.Now())
+}
+
+//receiveData stores the items of a DataResponse and records the versions of the owners it completes
+func (e *Engine) receiveData(dataresponse DataResponse) {
+	e.observeItems(dataresponse.Items)
+	completed := e.fetched(dataresponse.Items)
+	e.put(e.dropRetired(e.dropExpired(dataresponse.Items, e.clock.Now())))
+	for id, t := range dataresponse.AssociatedBuildTime {
+		if e.isRetired(id, t) {
+			continue
+		}
+		e.updateIndexTime(id, t)
+	}
+	// the DataRequests merged with fetches in flight or issued again carry no BuildTime
+	for id, t := range completed {
+		if previous, _ := e.getIndexTime(id); !previous.Before(t) || e.isRetired(id, t) {
+			continue
+		}
+		e.updateIndexTime(id, t)
+	}
+	e.checkRecovery(time.Now())
+}
+
+//diffKeys compares the keys of owner id and returns the keys that must be fetched and the ones that must be deleted to move from current to update.
+//A key missing from update is kept if it is newer than buildTime: it was pushed after the update was built
+func diffKeys(id ID, current StampedKeys, update StampedKeys, buildTime time.Time) (toFetch KeyIDPairs, toDelete KeyIDPairs) {
+	//index all keys and pair them
+	allKeys := map[Key]keyPair{}
+	for i, k := range current {
+		allKeys[k.Key] = keyPair{current: &current[i]}
+	}
+	for i, k := range update {
+		kp, ok := allKeys[k.Key]
+		if !ok {
+			kp = keyPair{update: &update[i]}
+		} else {
+			kp.update = &update[i]
+		}
+		allKeys[k.Key] = kp
+	}
+	toFetch = KeyIDPairs{}
+	toDelete = KeyIDPairs{}
+
+	//compare key version
+	for k, kp := range allKeys {
+		if kp.current == nil {
+			toFetch = append(toFetch, KeyIDPair{ID: id, Key: k})
+			continue
+		}
+		if kp.update == nil {
+			if kp.current.Timestamp.After(buildTime) {
 				continue
 			}
+			toDelete = append(toDelete, KeyIDPair{ID: id, Key: k})
+			continue
 		}
-		if len(toFetch) > 0 {
-			e.connector.RequestKeysChan() <- DataRequest{KeyIDPairs: toFetch, RequestDestination: indexMap.Source, RequestSource: e.local.ID(), AssociatedBuildTime: map[ID]time.Time{id: updateIndex.BuildTime}}
+		if kp.update.Timestamp.After(kp.current.Timestamp) {
+			toFetch = append(toFetch, KeyIDPair{ID: id, Key: k})
+			continue
 		}
-		if len(toDelete) > 0 {
-			e.local.Delete(toDelete)
-			e.updateIndexTime(id, updateIndex.BuildTime)
+	}
+	return toFetch, toDelete
+}
+
+//applyUpdates requests the keys to fetch to source and deletes the keys removed by the owner. update holds the
+//versions of the keys advertised by source, the keys already in flight are not requested again.
+//Nothing is requested to a dead source: the index of another neighbor will trigger the fetch
+func (e *Engine) applyUpdates(source ID, id ID, buildTime time.Time, toFetch KeyIDPairs, toDelete KeyIDPairs, update StampedKeys) {
+	if len(toFetch) > 0 && !e.isDead(source) {
+		fetch := e.trackFetches(source, buildTime, toFetch, update, time.Now())
+		rq := DataRequest{KeyIDPairs: fetch, RequestDestination: source, RequestSource: e.local.ID(), AssociatedBuildTime: map[ID]time.Time{id: buildTime}}
+		if len(fetch) < len(toFetch) {
+			rq.AssociatedBuildTime = nil // the version is complete only once the fetches in flight are received
+		}
+		if len(fetch) > 0 {
+			e.requestKeys(rq)
+		}
+	}
+	if len(toDelete) > 0 {
+		e.delete(toDelete)
+		if len(toFetch) == 0 {
+			// with keys to fetch, the BuildTime is recorded once they are received: the neighbors and the barriers
+			// read it as the proof that the shard holds that version
+			e.updateIndexTime(id, buildTime)
 		}
 	}
 }
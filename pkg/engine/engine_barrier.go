@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+//ErrBarrierTimeout is returned when the members did not acknowledge the write before the timeout of the barrier
+var ErrBarrierTimeout = errors.New("barrier timeout")
+
+//Barrier resolves when the selected neighbors advertise an index of the local member that holds a write: its
+//BuildTime is not older than the stamp of the write or it lists the key at that stamp or later.
+//A neighbor that is not interested in the key acknowledges it once it synced that version of the shard.
+//Only the neighbors acknowledge a write: the members reached through a route don't send their index to the local
+//member, a barrier can't wait for them
+type Barrier struct {
+	engine     *Engine
+	key        StampedKey
+	mutex      sync.Mutex
+	pending    map[ID]struct{}
+	err        error
+	done       chan struct{}
+	stopCancel func() bool
+}
+
+//Barrier returns a barrier on the write of the local member stamped by key. It waits for the given neighbors,
+//all the current neighbors if none is given; a member that is not a neighbor is rejected with ErrUnknownMember.
+//The barrier is registered until it resolves, it fails or ctx is done: it then fails with the error of ctx
+func (e *Engine) Barrier(ctx context.Context, key StampedKey, members ...ID) (*Barrier, error) {
+	b := &Barrier{
+		engine:     e,
+		key:        key,
+		pending:    map[ID]struct{}{},
+		done:       make(chan struct{}),
+		stopCancel: func() bool { return true },
+	}
+	e.membersMutex.Lock()
+	if len(members) == 0 {
+		for id := range e.members {
+			members = append(members, id)
+		}
+	}
+	for _, id := range members {
+		if _, ok := e.members[id]; !ok {
+			e.membersMutex.Unlock()
+			return nil, fmt.Errorf("barrier on %s: %w", id, ErrUnknownMember)
+		}
+		b.pending[id] = struct{}{}
+	}
+	e.membersMutex.Unlock()
+	if len(b.pending) == 0 {
+		close(b.done)
+		return b, nil
+	}
+	e.barriersMutex.Lock()
+	e.barriers[b] = struct{}{}
+	e.barriersMutex.Unlock()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.stopCancel = context.AfterFunc(ctx, func() {
+		b.Stop()
+		b.fail(fmt.Errorf("%s waiting for %v: %w", b.key.Key, b.Pending(), ctx.Err()))
+	})
+	return b, nil
+}
+
+//Done returns a channel closed when the barrier resolves or fails
+func (b *Barrier) Done() <-chan struct{} {
+	return b.done
+}
+
+//Err returns the reason of the failure of the barrier, nil if it resolved or is still waiting
+func (b *Barrier) Err() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.err
+}
+
+//Pending returns the members that did not acknowledge the write yet
+func (b *Barrier) Pending() []ID {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	pending := make([]ID, 0, len(b.pending))
+	for id := range b.pending {
+		pending = append(pending, id)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+	return pending
+}
+
+//Wait blocks until the barrier resolves, ctx is done or timeout elapses. A timeout of 0 only waits for ctx.
+//The barrier is stopped when Wait returns
+func (b *Barrier) Wait(ctx context.Context, timeout time.Duration) error {
+	defer b.Stop()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-b.done:
+		return b.Err()
+	case <-expired:
+		return fmt.Errorf("%s waiting for %v: %w", b.key.Key, b.Pending(), ErrBarrierTimeout)
+	case <-ctx.Done():
+		return fmt.Errorf("%s waiting for %v: %w", b.key.Key, b.Pending(), ctx.Err())
+	}
+}
+
+//Stop unregisters the barrier, it is no longer resolved
+func (b *Barrier) Stop() {
+	b.mutex.Lock()
+	stopCancel := b.stopCancel
+	b.mutex.Unlock()
+	stopCancel()
+	b.engine.barriersMutex.Lock()
+	defer b.engine.barriersMutex.Unlock()
+	delete(b.engine.barriers, b)
+}
+
+//acknowledged returns true if the index of the local member advertised by a neighbor holds the write
+func (b *Barrier) acknowledged(index Index) bool {
+	if index.Retired {
+		return false
+	}
+	if !index.BuildTime.Before(b.key.Timestamp) {
+		return true
+	}
+	for _, k := range index.StampedKeys {
+		if k.Key == b.key.Key && !k.Timestamp.Before(b.key.Timestamp) {
+			return true
+		}
+	}
+	return false
+}
+
+//resolve records the acknowledgment of member and closes the barrier when nothing is pending. err fails the barrier
+func (b *Barrier) resolve(member ID, err error) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if _, ok := b.pending[member]; !ok {
+		return false
+	}
+	select {
+	case <-b.done:
+		return true // failed meanwhile
+	default:
+	}
+	if err != nil {
+		b.err = err
+		close(b.done)
+		return true
+	}
+	delete(b.pending, member)
+	if len(b.pending) == 0 {
+		close(b.done)
+		return true
+	}
+	return false
+}
+
+//fail closes the barrier with err if it is still waiting
+func (b *Barrier) fail(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	select {
+	case <-b.done:
+		return
+	default:
+	}
+	b.err = err
+	close(b.done)
+}
+
+//heardBarriers resolves the barriers with the index of the local member advertised by the Source of the IndexMap
+func (e *Engine) heardBarriers(indexMap IndexMap) {
+	index, ok := indexMap.Indexes[e.local.ID()]
+	if !ok {
+		return
+	}
+	e.barriersMutex.Lock()
+	defer e.barriersMutex.Unlock()
+	for b := range e.barriers {
+		if b.acknowledged(index) && b.resolve(indexMap.Source, nil) {
+			delete(e.barriers, b)
+		}
+	}
+}
+
+//failBarriers fails the barriers waiting for a removed neighbor
+func (e *Engine) failBarriers(id ID) {
+	e.barriersMutex.Lock()
+	defer e.barriersMutex.Unlock()
+	for b := range e.barriers {
+		if b.resolve(id, fmt.Errorf("barrier on %s: %w", id, ErrDisconnected)) {
+			delete(e.barriers, b)
+		}
+	}
+}
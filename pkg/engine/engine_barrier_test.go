@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBarrier(t *testing.T) {
+	testBarrier(t)
+}
+
+func TestBarrierMerkle(t *testing.T) {
+	testBarrier(t, WithIndexMode(IndexModeMerkle))
+}
+
+func testBarrier(t *testing.T, options ...Option) {
+	members, engines := prepareTest(3, 0, "line", true, syncPeriod, options...)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	item := newTestItem("David", "Benque")
+	members[1].Write(item)
+	b, err := engines[1].Barrier(context.Background(), item.StampedKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Wait(context.Background(), 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if members[0].GetStore().Get(KeyIDPair{ID: members[1].ID(), Key: item.Key}) == nil {
+		t.Fatalf("the barrier resolved before %s received the write", members[0].ID())
+	}
+}
+
+func TestBarrierNoNeighbor(t *testing.T) {
+	members, engines := prepareTest(1, 0, "line", true, syncPeriod)
+	item := newTestItem("David", "Benque")
+	members[0].Write(item)
+	b, err := engines[0].Barrier(context.Background(), item.StampedKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-b.Done():
+	default:
+		t.Fatalf("a barrier without neighbor must be resolved")
+	}
+	if _, err := engines[0].Barrier(context.Background(), item.StampedKey(), "unknown"); !errors.Is(err, ErrUnknownMember) {
+		t.Fatalf("expected ErrUnknownMember, got %v", err)
+	}
+}
+
+func TestBarrierFailures(t *testing.T) {
+	members, engines := prepareTest(1, 0, "line", true, syncPeriod)
+	silent := newTestMember("silent", NewMapStore())
+	engines[0].AddMember(silent)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	item := newTestItem("David", "Benque")
+	members[0].Write(item)
+
+	b, _ := engines[0].Barrier(context.Background(), item.StampedKey())
+	if err := b.Wait(context.Background(), 5*syncPeriod); !errors.Is(err, ErrBarrierTimeout) {
+		t.Fatalf("expected ErrBarrierTimeout, got %v", err)
+	}
+	if p := b.Pending(); len(p) != 1 || p[0] != silent.ID() {
+		t.Fatalf("unexpected pending members %v", p)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b, _ = engines[0].Barrier(context.Background(), item.StampedKey())
+	if err := b.Wait(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled context, got %v", err)
+	}
+
+	b, _ = engines[0].Barrier(context.Background(), item.StampedKey(), silent.ID())
+	engines[0].RemoveMember(silent.ID())
+	if err := b.Wait(context.Background(), time.Second); !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("expected ErrDisconnected, got %v", err)
+	}
+}
+
+func TestBarrierDeleteWithFetchInFlight(t *testing.T) {
+	writer := newTestMember("M0", NewMapStore())
+	neighbor := newTestMember("M1", NewMapStore())
+	we := NewEngine(writer, time.Hour)
+	ne := NewEngine(neighbor, time.Hour)
+	we.AddMember(neighbor)
+	ne.AddMember(writer)
+	// deliver answers rq as the data loop of the neighbor does
+	deliver := func(rq DataRequest) {
+		items := writer.GetData(rq.KeyIDPairs)
+		ne.fetched(items)
+		ne.put(items)
+		for id, bt := range rq.AssociatedBuildTime {
+			ne.updateIndexTime(id, bt)
+		}
+	}
+
+	old := newTestItem("Dan", "Benque")
+	writer.Write(old)
+	writer.Write(newTestItem("Ben", "Benque"))
+	ne.CheckAndGetUpdates(we.buildIndexMap())
+	deliver(nextDataRequest(t, neighbor))
+
+	// in the same period the writer deletes a key and writes another one
+	writer.Remove(old.Key)
+	item := newTestItem("David", "Benque")
+	writer.Write(item)
+	b, err := we.Barrier(context.Background(), item.StampedKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ne.CheckAndGetUpdates(we.buildIndexMap())
+	rq := nextDataRequest(t, neighbor) // held back
+	we.CheckAndGetUpdates(ne.buildIndexMap())
+	select {
+	case <-b.Done():
+		t.Fatalf("the barrier resolved before %s received the write", neighbor.ID())
+	default:
+	}
+
+	deliver(rq)
+	we.CheckAndGetUpdates(ne.buildIndexMap())
+	select {
+	case <-b.Done():
+	default:
+		t.Fatalf("the barrier did not resolve once %s received the write", neighbor.ID())
+	}
+}
+
+func TestBarrierContext(t *testing.T) {
+	members, engines := prepareTest(1, 0, "line", true, syncPeriod)
+	silent := newTestMember("silent", NewMapStore())
+	engines[0].AddMember(silent)
+	item := newTestItem("David", "Benque")
+	members[0].Write(item)
+
+	// a barrier that is never waited is dropped with its context
+	ctx, cancel := context.WithCancel(context.Background())
+	b, _ := engines[0].Barrier(ctx, item.StampedKey())
+	cancel()
+	select {
+	case <-b.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("the barrier did not end with its context")
+	}
+	if err := b.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled context, got %v", err)
+	}
+	// or already done
+	b, _ = engines[0].Barrier(ctx, item.StampedKey())
+	if err := b.Wait(context.Background(), time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled context, got %v", err)
+	}
+	engines[0].barriersMutex.Lock()
+	defer engines[0].barriersMutex.Unlock()
+	if n := len(engines[0].barriers); n != 0 {
+		t.Fatalf("%d barriers are still registered", n)
+	}
+}
@@ -0,0 +1,167 @@
+package engine
+
+import "time"
+
+//deltaHistory is the number of versions of an owner index kept to answer the IndexRequests of the members that are behind
+const deltaHistory = 32
+
+//indexHistory records the keys that changed between the successive versions of an owner index
+type indexHistory struct {
+	base      time.Time // the changes made up to base have been compacted away
+	buildTime time.Time // BuildTime of the latest version
+	keys      map[Key]StampedKey
+	entries   []indexHistoryEntry
+	late      map[Key]struct{} // changed without a new BuildTime, they are part of the next version too
+}
+
+//indexHistoryEntry lists the keys that changed to produce the version buildTime
+type indexHistoryEntry struct {
+	buildTime time.Time
+	touched   map[Key]struct{}
+}
+
+func newIndexHistory() *indexHistory {
+	return &indexHistory{keys: map[Key]StampedKey{}, late: map[Key]struct{}{}}
+}
+
+//record adds the version buildTime of the index
+func (h *indexHistory) record(buildTime time.Time, keys StampedKeys) {
+	current := make(map[Key]StampedKey, len(keys))
+	touched := map[Key]struct{}{}
+	for _, k := range keys {
+		current[k.Key] = k
+		if old, ok := h.keys[k.Key]; !ok || !old.Timestamp.Equal(k.Timestamp) {
+			touched[k.Key] = struct{}{}
+		}
+	}
+	for k := range h.keys {
+		if _, ok := current[k]; !ok {
+			touched[k] = struct{}{}
+		}
+	}
+	h.keys = current
+	if h.buildTime.Before(buildTime) {
+		h.buildTime = buildTime
+	}
+	if l := len(h.entries); l > 0 && !h.entries[l-1].buildTime.Before(h.buildTime) {
+		// the content changed without a new BuildTime: some data of that version arrived late, or the data of the
+		// next one. The members that already hold that version get them with the next one
+		for k := range touched {
+			h.entries[l-1].touched[k] = struct{}{}
+			h.late[k] = struct{}{}
+		}
+		return
+	}
+	for k := range h.late {
+		touched[k] = struct{}{}
+	}
+	if len(touched) == 0 {
+		return
+	}
+	h.late = map[Key]struct{}{}
+	h.entries = append(h.entries, indexHistoryEntry{buildTime: h.buildTime, touched: touched})
+	if len(h.entries) > deltaHistory {
+		h.base = h.entries[0].buildTime
+		h.entries = h.entries[1:]
+	}
+}
+
+//delta returns the changes made to the index after the version since
+func (h *indexHistory) delta(since time.Time) IndexDelta {
+	d := IndexDelta{Since: since, StampedKeys: StampedKeys{}, Deleted: Keys{}}
+	if since.Before(h.base) {
+		d.Full = true
+		for _, k := range h.keys {
+			d.StampedKeys = append(d.StampedKeys, k)
+		}
+		return d
+	}
+	touched := map[Key]struct{}{}
+	for _, entry := range h.entries {
+		if !entry.buildTime.After(since) {
+			continue
+		}
+		for k := range entry.touched {
+			touched[k] = struct{}{}
+		}
+	}
+	for k := range touched {
+		if sk, ok := h.keys[k]; ok {
+			d.StampedKeys = append(d.StampedKeys, sk)
+		} else {
+			d.Deleted = append(d.Deleted, k)
+		}
+	}
+	return d
+}
+
+//recordIndexes records the indexes in the histories and removes their keys: only the BuildTime is advertised
+func (e *Engine) recordIndexes(indexMap IndexMap) IndexMap {
+	e.indexHistoriesMutex.Lock()
+	defer e.indexHistoriesMutex.Unlock()
+	for id, index := range indexMap.Indexes {
+		h, ok := e.indexHistories[id]
+		if !ok {
+			h = newIndexHistory()
+			e.indexHistories[id] = h
+		}
+		h.record(index.BuildTime, index.StampedKeys)
+		indexMap.Indexes[id] = Index{BuildTime: h.buildTime, Mode: IndexModeDelta}
+	}
+	return indexMap
+}
+
+//addDeltas adds to the response the changes requested since a known BuildTime
+func (e *Engine) addDeltas(rq IndexRequest, rs *IndexResponse) {
+	e.indexHistoriesMutex.RLock()
+	defer e.indexHistoriesMutex.RUnlock()
+	for id, since := range rq.Since {
+		h, ok := e.indexHistories[id]
+		if !ok || !since.Before(h.buildTime) {
+			continue
+		}
+		rs.BuildTime[id] = h.buildTime
+		rs.Deltas[id] = h.delta(since)
+	}
+}
+
+//applyDeltas fetches the keys added or updated and deletes the keys removed since the version known by the engine
+func (e *Engine) applyDeltas(rs IndexResponse, currentIndexes map[ID]Index, needs func(KeyIDPair) bool, now time.Time) {
+	for id, delta := range rs.Deltas {
+		if !e.syncsOwner(id) {
+			continue
+		}
+		buildTime := rs.BuildTime[id]
+		previous, _ := e.getIndexTime(id)
+		if !previous.Before(buildTime) || e.isStale(id, buildTime) {
+			continue // we have a better version
+		}
+
+		var toFetch, toDelete KeyIDPairs
+		if delta.Full {
+			toFetch, toDelete = diffKeys(id, currentIndexes[id].StampedKeys, delta.StampedKeys.unexpired(now), buildTime)
+		} else {
+			current := map[Key]StampedKey{}
+			for _, k := range currentIndexes[id].StampedKeys {
+				current[k.Key] = k
+			}
+			toFetch, toDelete = KeyIDPairs{}, KeyIDPairs{}
+			for _, k := range delta.StampedKeys.unexpired(now) {
+				if c, ok := current[k.Key]; !ok || k.Timestamp.After(c.Timestamp) {
+					toFetch = append(toFetch, KeyIDPair{ID: id, Key: k.Key})
+				}
+			}
+			for _, k := range delta.Deleted {
+				if c, ok := current[k]; ok && !c.Timestamp.After(buildTime) {
+					toDelete = append(toDelete, KeyIDPair{ID: id, Key: k})
+				}
+			}
+		}
+		toFetch = toFetch.filter(needs)
+		if len(toFetch) == 0 && len(toDelete) == 0 {
+			e.updateIndexTime(id, buildTime)
+			continue
+		}
+		e.applyUpdates(rs.Source, id, buildTime, toFetch, toDelete, delta.StampedKeys)
+	}
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func keysOf(d IndexDelta) string {
+	keys := []string{}
+	for _, k := range d.StampedKeys {
+		keys = append(keys, string(k.Key))
+	}
+	sort.Strings(keys)
+	deleted := []string{}
+	for _, k := range d.Deleted {
+		deleted = append(deleted, string(k))
+	}
+	sort.Strings(deleted)
+	return fmt.Sprintf("%v %v", keys, deleted)
+}
+
+func TestIndexHistoryDelta(t *testing.T) {
+	t0 := time.Now()
+	t1, t2, t3 := t0.Add(time.Second), t0.Add(2*time.Second), t0.Add(3*time.Second)
+	h := newIndexHistory()
+	h.record(t1, StampedKeys{{Key: "a", Timestamp: t0}, {Key: "b", Timestamp: t0}})
+	h.record(t2, StampedKeys{{Key: "a", Timestamp: t0}, {Key: "b", Timestamp: t2}, {Key: "c", Timestamp: t2}})
+	h.record(t3, StampedKeys{{Key: "b", Timestamp: t2}, {Key: "c", Timestamp: t2}})
+
+	if got := keysOf(h.delta(time.Time{})); got != "[b c] [a]" {
+		t.Fatalf("delta since the beginning: %s", got)
+	}
+	if got := keysOf(h.delta(t1)); got != "[b c] [a]" {
+		t.Fatalf("delta since t1: %s", got)
+	}
+	if got := keysOf(h.delta(t2)); got != "[] [a]" {
+		t.Fatalf("delta since t2: %s", got)
+	}
+	if h.delta(t1).Full {
+		t.Fatalf("unexpected full delta")
+	}
+}
+
+func TestIndexHistoryLateData(t *testing.T) {
+	t0 := time.Now()
+	t1 := t0.Add(time.Second)
+	h := newIndexHistory()
+	h.record(t1, StampedKeys{{Key: "a", Timestamp: t0}})
+	// same BuildTime, the data of that version is still arriving
+	h.record(t1, StampedKeys{{Key: "a", Timestamp: t0}, {Key: "b", Timestamp: t0}})
+	if len(h.entries) != 1 {
+		t.Fatalf("expected the changes to be merged in a single version, got %d", len(h.entries))
+	}
+	if got := keysOf(h.delta(time.Time{})); got != "[a b] []" {
+		t.Fatalf("delta since the beginning: %s", got)
+	}
+
+	// a member that already held t1 gets the late changes with the next version, even with the same content
+	t2 := t0.Add(2 * time.Second)
+	h.record(t2, StampedKeys{{Key: "a", Timestamp: t0}, {Key: "b", Timestamp: t0}})
+	if got := keysOf(h.delta(t1)); got != "[b] []" {
+		t.Fatalf("delta since t1: %s", got)
+	}
+	h.record(t2, StampedKeys{{Key: "b", Timestamp: t0}})
+	h.record(t2.Add(time.Second), StampedKeys{{Key: "b", Timestamp: t0}})
+	if got := keysOf(h.delta(t2)); got != "[] [a]" {
+		t.Fatalf("delta since t2: %s", got)
+	}
+}
+
+func TestIndexHistoryCompaction(t *testing.T) {
+	t0 := time.Now()
+	h := newIndexHistory()
+	for i := 0; i < deltaHistory+5; i++ {
+		h.record(t0.Add(time.Duration(i+1)*time.Second), StampedKeys{{Key: Key(fmt.Sprintf("k%d", i)), Timestamp: t0}})
+	}
+	if d := h.delta(t0.Add(time.Second)); !d.Full || len(d.StampedKeys) != 1 {
+		t.Fatalf("expected a full index for a compacted version, got %+v", d)
+	}
+	recent := t0.Add(time.Duration(deltaHistory+4) * time.Second)
+	if d := h.delta(recent); d.Full || keysOf(d) != fmt.Sprintf("[k%d] [k%d]", deltaHistory+4, deltaHistory+3) {
+		t.Fatalf("unexpected delta since the previous version: %+v", d)
+	}
+}
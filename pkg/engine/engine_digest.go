@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+//ErrNoDigest is returned when no digest of the shard was received from the peer
+var ErrNoDigest = errors.New("no digest")
+
+//ErrNotReplicated is returned when the local member does not replicate the whole shard of the owner
+var ErrNotReplicated = errors.New("shard not replicated")
+
+//Digest returns the hash of the keys. The order of the keys does not matter, the hash of no key is the zero Hash
+func (a StampedKeys) Digest() Hash {
+	if len(a) == 0 {
+		return Hash{}
+	}
+	keys := make(StampedKeys, len(a))
+	copy(keys, a)
+	sort.Sort(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k.Key))
+		h.Write([]byte{0})
+		binary.Write(h, binary.BigEndian, k.Timestamp.UnixNano())
+	}
+	var d Hash
+	copy(d[:], h.Sum(nil))
+	return d
+}
+
+//Hash returns the mesh-wide digest: the hash of the digests of all the owners. The BuildTimes are left out,
+//two members holding the same data have the same Hash
+func (d Digests) Hash() Hash {
+	owners := make([]ID, 0, len(d))
+	for id := range d {
+		owners = append(owners, id)
+	}
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+	h := sha256.New()
+	for _, id := range owners {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+		sum := d[id].Hash
+		h.Write(sum[:])
+	}
+	var sum Hash
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+//Digest returns the view of the local member on the shard of owner
+func (e *Engine) Digest(owner ID) Digest {
+	return e.Digests()[owner]
+}
+
+//Digests returns the views of the local member on the shards of all the owners it holds
+func (e *Engine) Digests() Digests {
+	now := e.clock.Now()
+	digests := Digests{}
+	for id, index := range e.local.GetIndexes().Indexes {
+		buildTime, _ := e.getIndexTime(id)
+		digests[id] = Digest{BuildTime: buildTime, Hash: index.StampedKeys.unexpired(now).Digest()}
+	}
+	return digests
+}
+
+//computeDigests returns the digests of the indexes before they are summarized or pruned
+func computeDigests(indexMap IndexMap) map[ID]Hash {
+	digests := make(map[ID]Hash, len(indexMap.Indexes))
+	for id, index := range indexMap.Indexes {
+		digests[id] = index.StampedKeys.Digest()
+	}
+	return digests
+}
+
+//addDigests sets the digests on the indexes that are advertised
+func addDigests(indexMap IndexMap, digests map[ID]Hash) IndexMap {
+	for id, index := range indexMap.Indexes {
+		if d, ok := digests[id]; ok {
+			index.Digest = d
+			indexMap.Indexes[id] = index
+		}
+	}
+	return indexMap
+}
+
+//heardDigests records the views of the Source of the IndexMap
+func (e *Engine) heardDigests(indexMap IndexMap) {
+	digests := Digests{}
+	for id, index := range indexMap.Indexes {
+		if index.Retired {
+			continue
+		}
+		digests[id] = Digest{BuildTime: index.BuildTime, Hash: index.Digest}
+	}
+	e.digestsMutex.Lock()
+	defer e.digestsMutex.Unlock()
+	e.peerDigests[indexMap.Source] = digests
+}
+
+//forgetDigests drops the views of a removed neighbor
+func (e *Engine) forgetDigests(id ID) {
+	e.digestsMutex.Lock()
+	defer e.digestsMutex.Unlock()
+	delete(e.peerDigests, id)
+}
+
+//PeerDigest returns the last view on the shard of owner advertised by the neighbor peer
+func (e *Engine) PeerDigest(peer, owner ID) (Digest, bool) {
+	e.digestsMutex.RLock()
+	defer e.digestsMutex.RUnlock()
+	d, ok := e.peerDigests[peer][owner]
+	return d, ok
+}
+
+//SameView returns true if the local member holds the same keys of owner as the neighbor peer in its last IndexMap.
+//The view of the peer is as old as the sync period: a recent write may not be reflected yet
+func (e *Engine) SameView(peer, owner ID) (bool, error) {
+	d, ok := e.PeerDigest(peer, owner)
+	if !ok {
+		return false, fmt.Errorf("owner %s from %s: %w", owner, peer, ErrNoDigest)
+	}
+	return e.Digest(owner).Hash == d.Hash, nil
+}
+
+//replicatesShard returns true if the interest selects all the keys of owner
+func replicatesShard(i Interest, owner ID) bool {
+	return len(i.KeyPrefixes) == 0 && i.Predicate == nil && i.MatchOwner(owner)
+}
+
+//converged returns true if the local member and all its live neighbors that replicate the shard of owner hold the
+//same keys at buildTime or later
+func (e *Engine) converged(owner ID, buildTime time.Time) bool {
+	local := e.Digest(owner)
+	if local.BuildTime.Before(buildTime) {
+		return false
+	}
+	e.membersMutex.Lock()
+	neighbors := make([]ID, 0, len(e.members))
+	for id := range e.members {
+		neighbors = append(neighbors, id)
+	}
+	e.membersMutex.Unlock()
+	for _, id := range neighbors {
+		if e.isDead(id) {
+			continue
+		}
+		e.interestsMutex.RLock()
+		interests, heard := e.neighborInterests[id]
+		mi, advertised := interests[id]
+		e.interestsMutex.RUnlock()
+		if heard && advertised && !replicatesShard(mi.Interest, owner) {
+			continue
+		}
+		d, ok := e.PeerDigest(id, owner)
+		if !ok || d.BuildTime.Before(buildTime) || d.Hash != local.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+//WaitConverged blocks until the local member and its live neighbors replicating the shard of owner hold the same
+//keys, in a version built at buildTime or later. It is checked at each sync period, until ctx is done
+func (e *Engine) WaitConverged(ctx context.Context, owner ID, buildTime time.Time) error {
+	if !replicatesShard(e.Interest(), owner) {
+		return fmt.Errorf("owner %s: %w", owner, ErrNotReplicated)
+	}
+	ticker := time.NewTicker(e.syncPeriod)
+	defer ticker.Stop()
+	for {
+		if e.converged(owner, buildTime) {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("owner %s did not converge: %w", owner, ctx.Err())
+		}
+	}
+}
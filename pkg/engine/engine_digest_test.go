@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStampedKeysDigest(t *testing.T) {
+	now := time.Now()
+	a := StampedKeys{{Key: "a", Timestamp: now}, {Key: "b", Timestamp: now}}
+	b := StampedKeys{{Key: "b", Timestamp: now}, {Key: "a", Timestamp: now}}
+	if a.Digest() != b.Digest() {
+		t.Fatalf("the digest depends on the order of the keys")
+	}
+	if a[0].Key != "a" {
+		t.Fatalf("the digest sorted the keys in place")
+	}
+	b[0].Timestamp = now.Add(time.Second)
+	if a.Digest() == b.Digest() {
+		t.Fatalf("the digest ignores the timestamps")
+	}
+	if (StampedKeys{}).Digest() != (Hash{}) {
+		t.Fatalf("the digest of no key is not the zero Hash")
+	}
+	if (Digests{"M0": {Hash: a.Digest()}}).Hash() == (Digests{"M0": {Hash: b.Digest()}}).Hash() {
+		t.Fatalf("the mesh digest ignores the digests of the owners")
+	}
+}
+
+func TestWaitConverged(t *testing.T) {
+	members, engines := prepareTest(3, 3, "line", true, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	members[0].Write(newTestItem("David", "Benque"))
+	waitForCount(t, 10, members, checkPeriod, 2*time.Second)
+
+	owner := members[0].ID()
+	var buildTime time.Time
+	for buildTime.IsZero() {
+		time.Sleep(syncPeriod)
+		buildTime = engines[0].Digest(owner).BuildTime
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, e := range engines {
+		if err := e.WaitConverged(ctx, owner, buildTime); err != nil {
+			t.Fatalf("%s: %v", e.local.ID(), err)
+		}
+	}
+	if same, err := engines[2].SameView(members[1].ID(), owner); err != nil || !same {
+		t.Fatalf("%s and %s have different views: %v", members[2].ID(), members[1].ID(), err)
+	}
+	if engines[1].Digests().Hash() != engines[2].Digests().Hash() {
+		t.Fatalf("the mesh digests differ")
+	}
+	if _, err := engines[1].SameView("unknown", owner); !errors.Is(err, ErrNoDigest) {
+		t.Fatalf("expected ErrNoDigest from an unknown member, got %v", err)
+	}
+}
+
+func TestWaitConvergedTimeout(t *testing.T) {
+	members, engines := prepareTest(1, 1, "line", true, syncPeriod)
+	engines[0].AddMember(newTestMember("silent", NewMapStore()))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*syncPeriod)
+	defer cancel()
+	if err := engines[0].WaitConverged(ctx, members[0].ID(), time.Time{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+
+	engines[0].SetInterest(Interest{KeyPrefixes: []Key{"user/"}})
+	if err := engines[0].WaitConverged(context.Background(), members[0].ID(), time.Time{}); !errors.Is(err, ErrNotReplicated) {
+		t.Fatalf("expected ErrNotReplicated, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+//RetryPolicy bounds the retries of the DataRequests that the connector failed to deliver.
+//The first retry waits Backoff, the delay doubles at each attempt up to MaxBackoff
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+//DefaultRetryPolicy is used by the engines created without WithRetry
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+//delay returns the time to wait before the given attempt
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+//WithRetry sets the retry policy of the failed DataRequests. Attempts 0 disables the retries
+func WithRetry(policy RetryPolicy) Option {
+	return func(e *Engine) {
+		e.retry = policy
+	}
+}
+
+//WithErrorHandler sets the function called with the errors the engine can't recover from: connector failures
+//that are not retried and DataRequests that failed after the last retry. The default handler logs them
+func WithErrorHandler(handler func(error)) Option {
+	return func(e *Engine) {
+		e.errorHandler = handler
+	}
+}
+
+func (e *Engine) logError(err error) {
+	log.Printf("Engine %s: %v", e.local.ID(), err)
+}
+
+//handleError retries the failed DataRequests, unless their destination is dead, and hands over the other errors to the error handler.
+//The keys of a request that is given up are requested to another neighbor that advertised them, if any.
+//The periodic index sync remains the last resort: a request that is given up is issued again on the next index.
+//The retries are tracked by loops so that the shutdown waits for them
+func (e *Engine) handleError(stop <-chan struct{}, loops *sync.WaitGroup, err error) {
+	if errors.Is(err, ErrDisconnected) {
+		return // canceled by RemoveMember
+	}
+	var cerr *ConnectorError
+	if !errors.As(err, &cerr) || cerr.Request == nil || cerr.Request.Attempt >= e.retry.Attempts || e.isDead(cerr.Request.RequestDestination) {
+		if cerr != nil && cerr.Request != nil && len(cerr.Request.KeyIDPairs) > 0 {
+			e.failFetches(*cerr.Request)
+		}
+		e.errorHandler(err)
+		return
+	}
+	rq := *cerr.Request
+	rq.Attempt++
+	disconnected := e.disconnectedChan(rq.RequestDestination)
+	loops.Add(1)
+	go func() {
+		defer loops.Done()
+		select {
+		case <-time.After(e.retry.delay(rq.Attempt)):
+		case <-disconnected:
+			return
+		case <-stop:
+			return
+		}
+		select {
+		case e.connector.RequestKeysChan() <- rq:
+		case <-stop:
+		}
+	}()
+}
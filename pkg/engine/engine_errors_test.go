@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Attempts: 5, Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for i, expected := range []time.Duration{10, 20, 40, 50, 50} {
+		attempt := i + 1
+		if d := p.delay(attempt); d != expected*time.Millisecond {
+			t.Fatalf("attempt %d: expected %v, got %v", attempt, expected*time.Millisecond, d)
+		}
+	}
+}
+
+func TestRetryTransientLoss(t *testing.T) {
+	retry := RetryPolicy{Attempts: 5, Backoff: 20 * time.Millisecond, MaxBackoff: 100 * time.Millisecond}
+	// the members are not connected yet: the first attempts fail
+	members := []*testMember{newTestMember("M0", NewMapStore()), newTestMember("M1", NewMapStore())}
+	members[0].Write(newTestItem("k", "v"))
+	engines := []*Engine{
+		NewEngine(members[0], time.Hour),
+		NewEngine(members[1], time.Hour, WithRetry(retry), WithErrorHandler(func(err error) {
+			t.Errorf("unexpected error: %v", err)
+		})),
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	engines[1].connector.RequestKeysChan() <- DataRequest{
+		RequestSource:      members[1].ID(),
+		RequestDestination: members[0].ID(),
+		KeyIDPairs:         KeyIDPairs{{ID: members[0].ID(), Key: "k"}},
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := engines[1].AddMember(members[0]); err != nil {
+		t.Fatal(err)
+	}
+	waitForCount(t, 1, members[1:], checkPeriod, 2*time.Second)
+	// the item of M0 reached M1 once the request was retried
+	if i := members[1].GetStore().Get(KeyIDPair{ID: members[0].ID(), Key: "k"}); i == nil || i.(*testItem).Value != "v" {
+		t.Fatalf("the retried request did not deliver the item, got %v", i)
+	}
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	errs := make(chan error, 10)
+	members, engines := prepareTest(1, 0, "line", true, time.Hour,
+		WithRetry(RetryPolicy{Attempts: 2, Backoff: time.Millisecond}),
+		WithErrorHandler(func(err error) { errs <- err }))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	engines[0].connector.RequestKeysChan() <- DataRequest{
+		RequestSource:      members[0].ID(),
+		RequestDestination: "lost",
+		KeyIDPairs:         KeyIDPairs{{ID: "lost", Key: "k"}},
+	}
+	select {
+	case err := <-errs:
+		var cerr *ConnectorError
+		if !errors.As(err, &cerr) || cerr.Request == nil {
+			t.Fatalf("expected a ConnectorError on a DataRequest, got %v", err)
+		}
+		if cerr.Request.Attempt != 2 {
+			t.Fatalf("expected the error after 2 retries, got %d", cerr.Request.Attempt)
+		}
+		// the engine knows no route toward lost
+		if !errors.Is(err, ErrNoRoute) {
+			t.Fatalf("expected ErrNoRoute, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the failure was not reported")
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("the failure was reported twice: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"time"
+)
+
+//Expired returns true if the version of the key is expired at now
+func (k StampedKey) Expired(now time.Time) bool {
+	return !k.Expires.IsZero() && !now.Before(k.Expires)
+}
+
+//unexpired returns the keys that are not expired at now
+func (a StampedKeys) unexpired(now time.Time) StampedKeys {
+	for i := range a {
+		if a[i].Expired(now) {
+			keys := make(StampedKeys, 0, len(a))
+			keys = append(keys, a[:i]...)
+			for _, k := range a[i+1:] {
+				if !k.Expired(now) {
+					keys = append(keys, k)
+				}
+			}
+			return keys
+		}
+	}
+	return a
+}
+
+//expireItems deletes from the local member the items expired at now, whatever their owner, and removes them from the
+//indexes about to be advertised. Each member expires its replicas on its own clock: it does not wait for the index
+//of the owner. The hybrid clock never goes back and is ahead of all the stamps observed, so an expired item is
+//never fetched again from a member whose clock is late
+func (e *Engine) expireItems(indexMap IndexMap, now time.Time) {
+	expired := KeyIDPairs{}
+	for id, index := range indexMap.Indexes {
+		keys := index.StampedKeys.unexpired(now)
+		if len(keys) == len(index.StampedKeys) {
+			continue
+		}
+		for _, k := range index.StampedKeys {
+			if k.Expired(now) {
+				expired = append(expired, KeyIDPair{ID: id, Key: k.Key})
+			}
+		}
+		index.StampedKeys = keys
+		indexMap.Indexes[id] = index
+	}
+	if len(expired) > 0 {
+		e.delete(expired)
+	}
+}
+
+//dropExpired removes the items that are already expired when they are received
+func (e *Engine) dropExpired(items Items, now time.Time) Items {
+	kept := make(Items, 0, len(items))
+	for _, i := range items {
+		if i.StampedKey().Expired(now) {
+			continue
+		}
+		kept = append(kept, i)
+	}
+	return kept
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStampedKeyExpired(t *testing.T) {
+	now := time.Now()
+	keys := StampedKeys{
+		{Key: "a", Timestamp: now},
+		{Key: "b", Timestamp: now, Expires: now},
+		{Key: "c", Timestamp: now, Expires: now.Add(time.Second)},
+	}
+	if keys[0].Expired(now) || !keys[1].Expired(now) || keys[2].Expired(now) {
+		t.Fatalf("unexpected expiration")
+	}
+	if u := keys.unexpired(now); len(u) != 2 || u[0].Key != "a" || u[1].Key != "c" {
+		t.Fatalf("unexpected unexpired keys %v", u)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	stop0 := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go engines[0].Run(stopContext(stop0))
+	runEngines(stop, engines[1:])
+
+	session := newTestItem("session", "David")
+	session.Expires = time.Now().Add(300 * time.Millisecond)
+	members[0].Write(session)
+	members[0].Write(newTestItem("David", "Benque"))
+	waitForCount(t, 2, members, checkPeriod, 2*time.Second)
+
+	// the replicas expire the item without the owner
+	close(stop0)
+	waitForCount(t, 1, members[1:], checkPeriod, 2*time.Second)
+	time.Sleep(10 * syncPeriod)
+	for _, m := range members[1:] {
+		if m.GetStore().Get(KeyIDPair{ID: members[0].ID(), Key: "session"}) != nil {
+			t.Fatalf("%s fetched the expired item again", m.ID())
+		}
+	}
+}
+
+func TestExpiryOwner(t *testing.T) {
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	session := newTestItem("session", "David")
+	session.Expires = time.Now().Add(300 * time.Millisecond)
+	members[0].Write(session)
+	members[0].Write(newTestItem("David", "Benque"))
+	waitForCount(t, 2, members, checkPeriod, 2*time.Second)
+
+	// the owner and the replicas drop the expired item, the other one stays
+	waitForCount(t, 1, members, checkPeriod, 2*time.Second)
+	for _, m := range members {
+		if m.GetStore().Get(KeyIDPair{ID: members[0].ID(), Key: "session"}) != nil {
+			t.Fatalf("%s still holds the expired item", m.ID())
+		}
+		if m.GetStore().Get(KeyIDPair{ID: members[0].ID(), Key: "David"}) == nil {
+			t.Fatalf("%s lost the item that does not expire", m.ID())
+		}
+	}
+}
+
+func TestExpiredNotFetched(t *testing.T) {
+	m := newTestMember("M1", NewMapStore())
+	e := NewEngine(m, time.Hour)
+	now := time.Now()
+	e.CheckAndGetUpdates(IndexMap{Source: "M0", Indexes: map[ID]Index{"M0": {
+		BuildTime: now,
+		StampedKeys: StampedKeys{
+			{Key: "expired", Timestamp: now.Add(-time.Minute), Expires: now.Add(-time.Second)},
+			{Key: "live", Timestamp: now.Add(-time.Minute)},
+		},
+	}}})
+	select {
+	case rq := <-m.connector.(*ConnectorImpl).RequestKeysCh:
+		if len(rq.KeyIDPairs) != 1 || rq.KeyIDPairs[0].Key != "live" {
+			t.Fatalf("unexpected request %v", rq.KeyIDPairs)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no data request")
+	}
+}
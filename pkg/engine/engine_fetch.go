@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//DefaultFetchTimeoutPeriods is the number of sync periods after which a fetch still in flight is issued again
+const DefaultFetchTimeoutPeriods = 10
+
+//inflightFetch is the newest version of a key requested and not received yet. The other neighbors that advertised
+//that version, or a newer one, are the alternates tried when the request fails
+type inflightFetch struct {
+	timestamp  time.Time
+	buildTime  time.Time // newest version of the owner that waits for that fetch
+	source     ID
+	deadline   time.Time
+	alternates []ID
+}
+
+//fetchedVersion is the newest version of an owner whose fetches were all received, and the newest version that lost
+//one of its fetches: the versions up to lost are not complete
+type fetchedVersion struct {
+	complete time.Time
+	lost     time.Time
+}
+
+//WithFetchTimeout sets how long a DataRequest is waited for before its keys are requested to another neighbor that
+//advertised them. Default is DefaultFetchTimeoutPeriods sync periods
+func WithFetchTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.fetchTimeout = timeout
+	}
+}
+
+//trackFetches records the keys requested to source for the version buildTime of their owner and returns the ones that
+//are not already in flight in the same version or a newer one. The keys left out are requested to source if the fetch
+//in flight fails
+func (e *Engine) trackFetches(source ID, buildTime time.Time, toFetch KeyIDPairs, update StampedKeys, now time.Time) KeyIDPairs {
+	stamps := map[Key]time.Time{}
+	for _, k := range update {
+		stamps[k.Key] = k.Timestamp
+	}
+	e.fetchesMutex.Lock()
+	defer e.fetchesMutex.Unlock()
+	fetch := KeyIDPairs{}
+	for _, kp := range toFetch {
+		stamp := stamps[kp.Key]
+		f, ok := e.fetches[kp]
+		if ok && !f.timestamp.Before(stamp) && now.Before(f.deadline) {
+			if f.source != source && !containsID(f.alternates, source) {
+				f.alternates = append(f.alternates, source)
+			}
+			if f.buildTime.Before(buildTime) {
+				f.buildTime = buildTime
+			}
+			atomic.AddUint64(&e.duplicateFetches, 1)
+			continue
+		}
+		if ok && buildTime.Before(f.buildTime) {
+			buildTime = f.buildTime // the version waiting for the expired fetch waits for that one
+		}
+		e.fetches[kp] = &inflightFetch{timestamp: stamp, buildTime: buildTime, source: source, deadline: now.Add(e.fetchTimeout)}
+		fetch = append(fetch, kp)
+	}
+	return fetch
+}
+
+//fetched forgets the fetches answered by the items received. It returns the newest version of each owner whose last
+//fetch in flight was answered, provided no fetch of that version was lost
+func (e *Engine) fetched(items Items) map[ID]time.Time {
+	e.fetchesMutex.Lock()
+	defer e.fetchesMutex.Unlock()
+	owners := map[ID]struct{}{}
+	for _, i := range items {
+		kp := KeyIDPair{ID: i.OwnedBy(), Key: i.GetKey()}
+		if f, ok := e.fetches[kp]; ok && !i.StampedKey().Timestamp.Before(f.timestamp) {
+			delete(e.fetches, kp)
+			v := e.fetchedVersions[kp.ID]
+			if v.complete.Before(f.buildTime) {
+				v.complete = f.buildTime
+			}
+			e.fetchedVersions[kp.ID] = v
+			owners[kp.ID] = struct{}{}
+		}
+	}
+	for kp := range e.fetches {
+		delete(owners, kp.ID)
+	}
+	completed := map[ID]time.Time{}
+	for id := range owners {
+		if v := e.fetchedVersions[id]; v.lost.Before(v.complete) {
+			completed[id] = v.complete
+		}
+		delete(e.fetchedVersions, id)
+	}
+	return completed
+}
+
+//reissueFetches requests again the fetches whose deadline is passed, to their next alternate
+func (e *Engine) reissueFetches(now time.Time) {
+	e.sendFetches(e.moveFetches(now, func(kp KeyIDPair, f *inflightFetch) bool { return !now.Before(f.deadline) }))
+}
+
+//failFetches requests to their next alternate the keys of the DataRequest rq that failed, or the keys in flight
+//toward a member that was removed when rq has no keys
+func (e *Engine) failFetches(rq DataRequest) {
+	keys := map[KeyIDPair]struct{}{}
+	for _, kp := range rq.KeyIDPairs {
+		keys[kp] = struct{}{}
+	}
+	e.sendFetches(e.moveFetches(time.Now(), func(kp KeyIDPair, f *inflightFetch) bool {
+		if f.source != rq.RequestDestination {
+			return false
+		}
+		_, ok := keys[kp]
+		return ok || len(rq.KeyIDPairs) == 0
+	}))
+}
+
+//moveFetches moves the selected fetches to their next alternate that is not dead. Past the last alternate the keys
+//are requested to their owner if a route leads to it, else the fetch is forgotten: the next index will issue it
+//again. It returns the keys to request by source
+func (e *Engine) moveFetches(now time.Time, selected func(KeyIDPair, *inflightFetch) bool) map[ID]KeyIDPairs {
+	e.fetchesMutex.Lock()
+	defer e.fetchesMutex.Unlock()
+	requests := map[ID]KeyIDPairs{}
+	for kp, f := range e.fetches {
+		if !selected(kp, f) {
+			continue
+		}
+		for len(f.alternates) > 0 && e.isDead(f.alternates[0]) {
+			f.alternates = f.alternates[1:]
+		}
+		if len(f.alternates) == 0 && f.source != kp.ID && kp.ID != e.local.ID() {
+			if _, ok := e.NextHop(kp.ID); ok {
+				f.alternates = []ID{kp.ID}
+			}
+		}
+		if len(f.alternates) == 0 {
+			delete(e.fetches, kp)
+			if v := e.fetchedVersions[kp.ID]; v.lost.Before(f.buildTime) {
+				v.lost = f.buildTime
+				e.fetchedVersions[kp.ID] = v
+			}
+			continue
+		}
+		f.source, f.alternates = f.alternates[0], f.alternates[1:]
+		f.deadline = now.Add(e.fetchTimeout)
+		requests[f.source] = append(requests[f.source], kp)
+	}
+	return requests
+}
+
+//sendFetches sends the DataRequests issued again. They carry no BuildTime: the BuildTime of the owner is recorded
+//once all its keys are received (see fetched)
+func (e *Engine) sendFetches(requests map[ID]KeyIDPairs) {
+	for source, kps := range requests {
+		e.requestKeys(DataRequest{KeyIDPairs: kps, RequestDestination: source, RequestSource: e.local.ID()})
+	}
+}
+
+//requestKeys hands the DataRequest to the connector, it is dropped once the engine stops
+func (e *Engine) requestKeys(rq DataRequest) {
+	select {
+	case e.connector.RequestKeysChan() <- rq:
+	case <-e.stopped:
+	}
+}
+
+//DuplicateFetches returns the number of keys not requested because the same version, or a newer one, was in flight
+func (e *Engine) DuplicateFetches() uint64 {
+	return atomic.LoadUint64(&e.duplicateFetches)
+}
+
+func containsID(ids []ID, id ID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func nextDataRequest(t *testing.T, m *testMember) DataRequest {
+	select {
+	case rq := <-m.connector.(*ConnectorImpl).RequestKeysCh:
+		return rq
+	case <-time.After(time.Second):
+		t.Fatalf("no data request")
+	}
+	return DataRequest{}
+}
+
+func noDataRequest(t *testing.T, m *testMember) {
+	if n := len(m.connector.(*ConnectorImpl).RequestKeysCh); n != 0 {
+		t.Fatalf("unexpected data requests: %d", n)
+	}
+}
+
+func advertise(e *Engine, source ID, buildTime time.Time, keys StampedKeys) {
+	e.CheckAndGetUpdates(IndexMap{Source: source, Indexes: map[ID]Index{"M0": {BuildTime: buildTime, StampedKeys: keys}}})
+}
+
+func TestFetchDeduplicated(t *testing.T) {
+	m := newTestMember("M1", NewMapStore())
+	e := NewEngine(m, time.Hour, WithFetchTimeout(time.Minute))
+	now := time.Now()
+	keys := StampedKeys{{Key: "David", Timestamp: now}}
+	advertise(e, "A", now, keys)
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "A" || rq.AssociatedBuildTime == nil {
+		t.Fatalf("unexpected request %+v", rq)
+	}
+	advertise(e, "B", now, keys)
+	noDataRequest(t, m)
+	if d := e.DuplicateFetches(); d != 1 {
+		t.Fatalf("expected 1 duplicate fetch, got %d", d)
+	}
+
+	// a newer version is fetched
+	newer := StampedKeys{{Key: "David", Timestamp: now.Add(time.Second)}, {Key: "Benque", Timestamp: now}}
+	advertise(e, "B", now.Add(time.Second), newer)
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "B" || len(rq.KeyIDPairs) != 2 {
+		t.Fatalf("unexpected request %+v", rq)
+	}
+	advertise(e, "C", now.Add(2*time.Second), StampedKeys{{Key: "David", Timestamp: now.Add(time.Second)}, {Key: "Dan", Timestamp: now}})
+	if rq := nextDataRequest(t, m); len(rq.KeyIDPairs) != 1 || rq.KeyIDPairs[0].Key != "Dan" || rq.AssociatedBuildTime != nil {
+		t.Fatalf("expected a request of the key not in flight without BuildTime, got %+v", rq)
+	}
+
+	// the received items end the fetches
+	item := newTestItem("David", "Benque")
+	item.Owner = "M0"
+	item.Time = now.Add(time.Second)
+	e.fetched(Items{item})
+	advertise(e, "A", now.Add(3*time.Second), StampedKeys{{Key: "David", Timestamp: now.Add(time.Second)}})
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "A" {
+		t.Fatalf("unexpected request %+v", rq)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	m := newTestMember("M1", NewMapStore())
+	e := NewEngine(m, time.Hour, WithFetchTimeout(time.Minute))
+	for _, id := range []string{"A", "B"} {
+		e.AddMember(newTestMember(id, NewMapStore()))
+	}
+	now := time.Now()
+	keys := StampedKeys{{Key: "David", Timestamp: now}}
+	advertise(e, "A", now, keys)
+	nextDataRequest(t, m)
+	advertise(e, "B", now, keys)
+
+	e.reissueFetches(time.Now())
+	noDataRequest(t, m)
+	e.reissueFetches(time.Now().Add(time.Minute))
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "B" || len(rq.KeyIDPairs) != 1 {
+		t.Fatalf("the fetch was not issued again to B: %+v", rq)
+	}
+	// no alternate left, the owner is reached through its route
+	e.reissueFetches(time.Now().Add(2 * time.Minute))
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "M0" {
+		t.Fatalf("the fetch was not issued to the owner: %+v", rq)
+	}
+	// then the next index issues it
+	e.reissueFetches(time.Now().Add(3 * time.Minute))
+	noDataRequest(t, m)
+	advertise(e, "A", now, keys)
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "A" {
+		t.Fatalf("unexpected request %+v", rq)
+	}
+}
+
+func TestFetchFailed(t *testing.T) {
+	m := newTestMember("M1", NewMapStore())
+	e := NewEngine(m, time.Hour, WithRetry(RetryPolicy{}), WithErrorHandler(func(error) {}))
+	now := time.Now()
+	keys := StampedKeys{{Key: "David", Timestamp: now}}
+	advertise(e, "A", now, keys)
+	rq := nextDataRequest(t, m)
+	advertise(e, "B", now, keys)
+	advertise(e, "C", now, keys)
+
+	e.handleError(nil, &sync.WaitGroup{}, &ConnectorError{Op: "ForwardDataRequest", Request: &rq, Err: errors.New("boom")})
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "B" {
+		t.Fatalf("the fetch was not issued again to B: %+v", rq)
+	}
+	// B is removed, C is the last one
+	e.RemoveMember("B")
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "C" {
+		t.Fatalf("the fetch was not issued again to C: %+v", rq)
+	}
+}
+
+func TestFetchStopped(t *testing.T) {
+	m := newTestMember("M1", NewMapStore())
+	e := NewEngine(m, time.Hour)
+	for i := 0; i < DefaultConnectorQueueSize; i++ {
+		m.connector.RequestKeysChan() <- DataRequest{}
+	}
+	close(e.stopped)
+	done := make(chan struct{})
+	go func() {
+		e.sendFetches(map[ID]KeyIDPairs{"A": {{ID: "M0", Key: "David"}}})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("the stopped engine is blocked on the full queue of the connector")
+	}
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//localIndexCache keeps the indexes of the local member between the changes made by the engine, and the MerkleTrees
+//built on them. The generation is bumped by each change so that an index read before a change is not cached after it
+type localIndexCache struct {
+	sync.Mutex
+	generation  uint64
+	indexes     map[ID]Index
+	merkleTrees map[ID]*MerkleTree
+}
+
+//localIndexes returns the indexes of the local member, read once until the next change made by the engine or the
+//next tick. The map is shared: it must not be modified
+func (e *Engine) localIndexes() map[ID]Index {
+	c := &e.localIndexCache
+	c.Lock()
+	if c.indexes != nil {
+		defer c.Unlock()
+		return c.indexes
+	}
+	generation := c.generation
+	c.Unlock()
+	indexes := e.local.GetIndexes().Indexes
+	c.Lock()
+	defer c.Unlock()
+	if c.generation == generation {
+		c.indexes = indexes
+	}
+	return indexes
+}
+
+//invalidateLocalIndexes forgets the cached indexes of the local member
+func (e *Engine) invalidateLocalIndexes() {
+	c := &e.localIndexCache
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	c.indexes = nil
+	c.merkleTrees = nil
+}
+
+//coalesceIndexMaps reads the IndexMaps queued behind first and keeps the latest one of each source, by Clock, in the
+//order the sources were received. Each IndexMap read is a heartbeat of its source and its clock is observed
+func (e *Engine) coalesceIndexMaps(first IndexMap) []IndexMap {
+	latest := map[ID]int{}
+	maps := []IndexMap{}
+	add := func(im IndexMap) {
+		e.failureDetector.heard(im.Source, time.Now())
+		e.clock.Observe(im.Clock)
+		if i, ok := latest[im.Source]; ok {
+			if !im.Clock.Before(maps[i].Clock) {
+				maps[i] = im
+			}
+			atomic.AddUint64(&e.coalescedIndexMaps, 1)
+			return
+		}
+		latest[im.Source] = len(maps)
+		maps = append(maps, im)
+	}
+	add(first)
+	// only the synch in goroutine reads the channel: the queued maps are there
+	for n := len(e.connector.ReceiveIndexChan()); n > 0; n-- {
+		add(<-e.connector.ReceiveIndexChan())
+	}
+	return maps
+}
+
+//CoalescedIndexMaps returns the number of IndexMaps skipped because a newer one of the same source was queued
+func (e *Engine) CoalescedIndexMaps() uint64 {
+	return atomic.LoadUint64(&e.coalescedIndexMaps)
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+//countingMember counts the reads of its indexes
+type countingMember struct {
+	*testMember
+	reads int
+}
+
+func (m *countingMember) GetIndexes() IndexMap {
+	m.reads++
+	return m.testMember.GetIndexes()
+}
+
+func TestCoalesceIndexMaps(t *testing.T) {
+	m := newTestMember("M0", NewMapStore())
+	e := NewEngine(m, syncPeriod)
+	now := time.Now()
+	queued := []IndexMap{
+		{Source: "A", Clock: now.Add(3 * time.Second)},
+		{Source: "B", Clock: now},
+		{Source: "A", Clock: now.Add(2 * time.Second)},
+	}
+	for _, im := range queued {
+		m.connector.(*ConnectorImpl).ReceiveIndexCh <- im
+	}
+	maps := e.coalesceIndexMaps(IndexMap{Source: "A", Clock: now.Add(time.Second)})
+	if len(maps) != 2 || maps[0].Source != "A" || maps[1].Source != "B" {
+		t.Fatalf("expected the maps of A then B, got %v", maps)
+	}
+	if !maps[0].Clock.Equal(now.Add(3 * time.Second)) {
+		t.Fatalf("the latest map of A was not kept")
+	}
+	if c := e.CoalescedIndexMaps(); c != 2 {
+		t.Fatalf("expected 2 coalesced maps, got %d", c)
+	}
+	if len(m.connector.ReceiveIndexChan()) != 0 {
+		t.Fatalf("the queued maps were not consumed")
+	}
+}
+
+func TestLocalIndexesCache(t *testing.T) {
+	m := &countingMember{testMember: newTestMember("M0", NewMapStore())}
+	e := NewEngine(m, syncPeriod)
+	e.localIndexes()
+	e.localIndexes()
+	if m.reads != 1 {
+		t.Fatalf("expected 1 read of the indexes, got %d", m.reads)
+	}
+
+	item := newTestItem("David", "Benque")
+	item.Owner = "M1"
+	e.put(Items{item})
+	if len(e.localIndexes()["M1"].StampedKeys) != 1 {
+		t.Fatalf("the cache was not invalidated by the change")
+	}
+	if m.reads != 2 {
+		t.Fatalf("expected 2 reads of the indexes, got %d", m.reads)
+	}
+}
+
+func TestLocalMerkleTreeCache(t *testing.T) {
+	m := newTestMember("M0", NewMapStore())
+	e := NewEngine(m, syncPeriod)
+	item := newTestItem("David", "Benque")
+	item.Owner = "M1"
+	e.put(Items{item})
+	tree := e.localMerkleTree("M1", 2)
+	if e.localMerkleTree("M1", 2) != tree {
+		t.Fatalf("the tree was built again without a change")
+	}
+	if other := e.localMerkleTree("M1", 3); other == tree || other.Depth() != 3 {
+		t.Fatalf("expected a tree of depth 3")
+	}
+
+	item = newTestItem("Dan", "Benque")
+	item.Owner = "M1"
+	e.put(Items{item})
+	tree = e.localMerkleTree("M1", 3)
+	if len(tree.Keys(0)) != 2 {
+		t.Fatalf("the tree was not invalidated by the change")
+	}
+}
@@ -0,0 +1,327 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+//all returns true if the interest selects all the data
+func (i Interest) all() bool {
+	return len(i.Owners) == 0 && len(i.KeyPrefixes) == 0 && i.Predicate == nil
+}
+
+//MatchOwner returns true if the interest may select items of owner id
+func (i Interest) MatchOwner(id ID) bool {
+	if len(i.Owners) == 0 {
+		return true
+	}
+	for _, o := range i.Owners {
+		if o == id {
+			return true
+		}
+	}
+	return false
+}
+
+//Match returns true if the interest selects the item kp
+func (i Interest) Match(kp KeyIDPair) bool {
+	if !i.MatchOwner(kp.ID) {
+		return false
+	}
+	if len(i.KeyPrefixes) > 0 {
+		found := false
+		for _, p := range i.KeyPrefixes {
+			if strings.HasPrefix(string(kp.Key), string(p)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return i.Predicate == nil || i.Predicate(kp)
+}
+
+//matchAny returns true if one of the interests selects kp
+func matchAny(interests []Interest, kp KeyIDPair) bool {
+	for _, i := range interests {
+		if i.Match(kp) {
+			return true
+		}
+	}
+	return false
+}
+
+func (kps KeyIDPairs) filter(keep func(KeyIDPair) bool) KeyIDPairs {
+	kept := make(KeyIDPairs, 0, len(kps))
+	for _, kp := range kps {
+		if keep(kp) {
+			kept = append(kept, kp)
+		}
+	}
+	return kept
+}
+
+//WithInterest declares the data needed by the local member. Default is all the data of the mesh
+func WithInterest(interest Interest) Option {
+	return func(e *Engine) {
+		e.interest.Interest = interest
+	}
+}
+
+//SetInterest changes the data needed by the local member, the neighbors learn it with the next IndexMap.
+//The data that is no longer needed is kept
+func (e *Engine) SetInterest(interest Interest) {
+	e.interestsMutex.Lock()
+	e.interest = MemberInterest{Interest: interest, Version: e.clock.Now(), Via: e.local.ID()}
+	e.interestsMutex.Unlock()
+	e.resetIndexTimes()
+}
+
+//Interest returns the interest of the local member
+func (e *Engine) Interest() Interest {
+	e.interestsMutex.RLock()
+	defer e.interestsMutex.RUnlock()
+	return e.interest.Interest
+}
+
+//advertisedInterests returns the interest of the local member and the ones learnt from the neighbors
+func (e *Engine) advertisedInterests() map[ID]MemberInterest {
+	e.interestsMutex.RLock()
+	defer e.interestsMutex.RUnlock()
+	interests := make(map[ID]MemberInterest, len(e.knownInterests)+1)
+	for id, mi := range e.knownInterests {
+		interests[id] = mi
+	}
+	own := e.interest
+	own.Predicate = nil
+	interests[e.local.ID()] = own
+	return interests
+}
+
+func equalInterests(a, b []Interest) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i].Owners) != len(b[i].Owners) || len(a[i].KeyPrefixes) != len(b[i].KeyPrefixes) {
+			return false
+		}
+		for j := range a[i].Owners {
+			if a[i].Owners[j] != b[i].Owners[j] {
+				return false
+			}
+		}
+		for j := range a[i].KeyPrefixes {
+			if a[i].KeyPrefixes[j] != b[i].KeyPrefixes[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//heardInterests records the interests served by a neighbor and the scope of its indexes. The BuildTimes of the
+//owners are forgotten when the data needed by the local member changes or when the neighbor prunes its indexes
+//differently: the next index of each owner is compared again and the data that became needed is fetched
+func (e *Engine) heardInterests(indexMap IndexMap) {
+	interests := indexMap.Interests
+	if interests == nil {
+		interests = map[ID]MemberInterest{}
+	}
+	e.interestsMutex.Lock()
+	previous, heard := e.neighborInterests[indexMap.Source]
+	changed := !heard || len(previous) != len(interests)
+	rescoped := !equalInterests(e.neighborScopes[indexMap.Source], indexMap.Scope)
+	e.neighborScopes[indexMap.Source] = indexMap.Scope
+	for id, mi := range interests {
+		if p, ok := previous[id]; !ok || !p.Version.Equal(mi.Version) || p.Via != mi.Via {
+			changed = true
+		}
+		if id == e.local.ID() || mi.Via == e.local.ID() {
+			continue // learnt from the local member
+		}
+		if k, ok := e.knownInterests[id]; !ok || mi.Version.After(k.Version) {
+			mi.Via = indexMap.Source
+			e.knownInterests[id] = mi
+		}
+	}
+	e.neighborInterests[indexMap.Source] = interests
+	// the relayed interests don't change what is needed by a member interested in everything
+	changed = changed && !e.interest.all()
+	e.interestsMutex.Unlock()
+	if changed || rescoped {
+		e.resetIndexTimes()
+	}
+}
+
+//forgetInterests drops the interests served by a removed neighbor
+func (e *Engine) forgetInterests(id ID) {
+	e.interestsMutex.Lock()
+	defer e.interestsMutex.Unlock()
+	delete(e.neighborInterests, id)
+	delete(e.neighborScopes, id)
+}
+
+//resetIndexTimes forgets the BuildTimes of the other owners. They are kept as floors: the older indexes are still ignored
+func (e *Engine) resetIndexTimes() {
+	e.indexTimeCacheMutext.Lock()
+	defer e.indexTimeCacheMutext.Unlock()
+	for id, t := range e.indexTimeCache {
+		if id == e.local.ID() {
+			continue
+		}
+		if t.After(e.indexTimeFloor[id]) {
+			e.indexTimeFloor[id] = t
+		}
+		delete(e.indexTimeCache, id)
+	}
+}
+
+//isStale returns true if an index of owner id built at buildTime is older than the version known before a reset
+func (e *Engine) isStale(id ID, buildTime time.Time) bool {
+	e.indexTimeCacheMutext.RLock()
+	defer e.indexTimeCacheMutext.RUnlock()
+	return buildTime.Before(e.indexTimeFloor[id])
+}
+
+//unheardMembers returns true if a member added to the engine did not advertise its interests yet
+func (e *Engine) unheardMembers() bool {
+	e.membersMutex.Lock()
+	defer e.membersMutex.Unlock()
+	e.interestsMutex.RLock()
+	defer e.interestsMutex.RUnlock()
+	for id := range e.members {
+		if _, ok := e.neighborInterests[id]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+//originInterest is an interest relayed for the member origin
+type originInterest struct {
+	origin ID
+	Interest
+}
+
+//needs returns the filter of the items the local member stores when they come from source: its own items, the ones
+//it is interested in and the ones it relays to the other neighbors that sent it an IndexMap. The items are never
+//relayed to their owner nor back to source. A neighbor that was not heard yet needs nothing: its interests will
+//reset the BuildTimes
+func (e *Engine) needs(source ID) func(KeyIDPair) bool {
+	e.interestsMutex.RLock()
+	defer e.interestsMutex.RUnlock()
+	own := e.interest.Interest
+	if own.all() {
+		return func(KeyIDPair) bool { return true }
+	}
+	relayed := []originInterest{}
+	for id, interests := range e.neighborInterests {
+		if id == source {
+			continue
+		}
+		if len(interests) == 0 {
+			// a neighbor that does not advertise its interests needs everything
+			return func(KeyIDPair) bool { return true }
+		}
+		for origin, mi := range interests {
+			if mi.Via == e.local.ID() {
+				continue
+			}
+			relayed = append(relayed, originInterest{origin: origin, Interest: mi.Interest})
+		}
+	}
+	local := e.local.ID()
+	return func(kp KeyIDPair) bool {
+		if kp.ID == local || own.Match(kp) {
+			return true
+		}
+		for _, r := range relayed {
+			if kp.ID != r.origin && r.Match(kp) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//scope returns the interests used to prune the indexes sent to the neighbors, nil if they can't be pruned.
+//Each neighbor is sent the index of its own items, so that it can recover them
+func (e *Engine) scope() []Interest {
+	if e.unheardMembers() {
+		return nil
+	}
+	e.interestsMutex.RLock()
+	defer e.interestsMutex.RUnlock()
+	if len(e.neighborInterests) == 0 {
+		return nil
+	}
+	// sorted so that the neighbors see the same scope while the interests don't change
+	neighbors := make([]ID, 0, len(e.neighborInterests))
+	for id := range e.neighborInterests {
+		neighbors = append(neighbors, id)
+	}
+	sort.Slice(neighbors, func(i, j int) bool { return neighbors[i] < neighbors[j] })
+	scope := []Interest{}
+	for _, id := range neighbors {
+		interests := e.neighborInterests[id]
+		if len(interests) == 0 {
+			return nil // needs everything
+		}
+		scope = append(scope, Interest{Owners: []ID{id}})
+		origins := make([]ID, 0, len(interests))
+		for origin := range interests {
+			origins = append(origins, origin)
+		}
+		sort.Slice(origins, func(i, j int) bool { return origins[i] < origins[j] })
+		for _, origin := range origins {
+			mi := interests[origin]
+			if mi.Via == e.local.ID() {
+				continue
+			}
+			if mi.all() {
+				return nil
+			}
+			scope = append(scope, Interest{Owners: mi.Owners, KeyPrefixes: mi.KeyPrefixes})
+		}
+	}
+	return scope
+}
+
+//pruneIndexes removes from the IndexMap the owners and the keys that no neighbor needs. The keys are only pruned
+//from the indexes of IndexModeFull, the other modes don't list them. The Scope is only set when it is not empty
+func (e *Engine) pruneIndexes(indexMap IndexMap) IndexMap {
+	scope := e.scope()
+	if len(scope) == 0 {
+		return indexMap
+	}
+	for id, index := range indexMap.Indexes {
+		owned := false
+		for _, i := range scope {
+			if i.MatchOwner(id) {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			delete(indexMap.Indexes, id)
+			continue
+		}
+		if index.Mode != IndexModeFull {
+			continue
+		}
+		keys := make(StampedKeys, 0, len(index.StampedKeys))
+		for _, k := range index.StampedKeys {
+			if matchAny(scope, KeyIDPair{ID: id, Key: k.Key}) {
+				keys = append(keys, k)
+			}
+		}
+		index.StampedKeys = keys
+		indexMap.Indexes[id] = index
+	}
+	indexMap.Scope = scope
+	return indexMap
+}
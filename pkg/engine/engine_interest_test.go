@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterestMatch(t *testing.T) {
+	i := Interest{Owners: []ID{"M0"}, KeyPrefixes: []Key{"user/", "group/"}}
+	tests := []struct {
+		kp    KeyIDPair
+		match bool
+	}{
+		{KeyIDPair{ID: "M0", Key: "user/David"}, true},
+		{KeyIDPair{ID: "M0", Key: "group/admin"}, true},
+		{KeyIDPair{ID: "M0", Key: "David"}, false},
+		{KeyIDPair{ID: "M1", Key: "user/David"}, false},
+	}
+	for _, tt := range tests {
+		if got := i.Match(tt.kp); got != tt.match {
+			t.Errorf("Match(%v) = %v, expected %v", tt.kp, got, tt.match)
+		}
+	}
+	i.Predicate = func(kp KeyIDPair) bool { return kp.Key != "user/root" }
+	if i.Match(KeyIDPair{ID: "M0", Key: "user/root"}) {
+		t.Errorf("the predicate is not applied")
+	}
+	if !(Interest{}).Match(KeyIDPair{ID: "M1", Key: "David"}) {
+		t.Errorf("the empty interest must select everything")
+	}
+}
+
+func TestInterestLeaf(t *testing.T) {
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	engines[2].SetInterest(Interest{Owners: []ID{"M0"}, KeyPrefixes: []Key{"user/"}})
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	members[0].Write(newTestItem("user/David", "Benque"))
+	members[0].Write(newTestItem("group/admin", "David"))
+	members[1].Write(newTestItem("user/Gemma", "Cotton"))
+	waitForCount(t, 3, members[:2], checkPeriod, 2*time.Second)
+	waitForCount(t, 1, members[2:], checkPeriod, 2*time.Second)
+
+	time.Sleep(10 * syncPeriod)
+	if c := members[2].GetStore().(*MapStore).Count(); c != 1 {
+		t.Fatalf("%s stores %d items, expected 1", members[2].ID(), c)
+	}
+	if members[2].GetStore().Get(KeyIDPair{ID: "M0", Key: "user/David"}) == nil {
+		t.Fatalf("%s did not receive the item it is interested in", members[2].ID())
+	}
+}
+
+func TestInterestRelay(t *testing.T) {
+	// M1 is only interested in its own items but relays the items of M0 to M2
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	engines[0].SetInterest(Interest{Owners: []ID{"M0"}})
+	engines[1].SetInterest(Interest{Owners: []ID{"M1"}})
+	engines[2].SetInterest(Interest{Owners: []ID{"M0"}})
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	for _, m := range members {
+		m.Write(newTestItem(Key("k"), string(m.ID())))
+	}
+	expected := []int{1, 2, 2}
+	for i, m := range members {
+		waitForCount(t, expected[i], []*testMember{m}, checkPeriod, 2*time.Second)
+	}
+	time.Sleep(10 * syncPeriod)
+	for i, m := range members {
+		if c := m.GetStore().(*MapStore).Count(); c != expected[i] {
+			t.Fatalf("%s stores %d items, expected %d", m.ID(), c, expected[i])
+		}
+	}
+	if members[1].GetStore().Get(KeyIDPair{ID: "M2", Key: "k"}) != nil {
+		t.Fatalf("the relay stores items that nobody needs")
+	}
+
+	// M0 widens its interest: the items of M1 and M2 become needed, M1 now stores the item of M2 to relay it
+	engines[0].SetInterest(Interest{})
+	expected = []int{3, 3, 2}
+	for i, m := range members {
+		waitForCount(t, expected[i], []*testMember{m}, checkPeriod, 2*time.Second)
+	}
+	time.Sleep(10 * syncPeriod)
+	for i, m := range members {
+		if c := m.GetStore().(*MapStore).Count(); c != expected[i] {
+			t.Fatalf("%s stores %d items after the widening, expected %d", m.ID(), c, expected[i])
+		}
+	}
+}
+
+func TestInterestPrunedIndexDeletesNothing(t *testing.T) {
+	members, engines := prepareTest(2, 0, "line", true, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	members[0].Write(newTestItem("group/admin", "David"))
+	members[0].Write(newTestItem("user/David", "Benque"))
+	waitForCount(t, 2, members, checkPeriod, 2*time.Second)
+
+	// the indexes sent by M0 don't list group/admin anymore, M1 must keep it
+	engines[1].SetInterest(Interest{KeyPrefixes: []Key{"user/"}})
+	members[0].Write(newTestItem("user/Gemma", "Cotton"))
+	waitForCount(t, 3, members, checkPeriod, 2*time.Second)
+	time.Sleep(10 * syncPeriod)
+	if c := members[1].GetStore().(*MapStore).Count(); c != 3 {
+		t.Fatalf("%s stores %d items, expected 3", members[1].ID(), c)
+	}
+	if members[1].GetStore().Get(KeyIDPair{ID: members[0].ID(), Key: "group/admin"}) == nil {
+		t.Fatalf("an item out of the scope of the pruned index was deleted")
+	}
+}
+
+func TestInterestScope(t *testing.T) {
+	e := NewEngine(newTestMember("M1", NewMapStore()), time.Hour)
+	e.AddMember(newTestMember("M2", NewMapStore()))
+	indexMap := IndexMap{Indexes: map[ID]Index{"M0": {}, "M1": {}}}
+	if scope := e.pruneIndexes(indexMap).Scope; scope != nil {
+		t.Fatalf("the indexes are pruned before the interests of M2 are known: %v", scope)
+	}
+	e.heardInterests(IndexMap{Source: "M2", Interests: map[ID]MemberInterest{
+		"M2": {Interest: Interest{Owners: []ID{"M0"}}, Version: time.Now(), Via: "M2"},
+	}})
+	pruned := e.pruneIndexes(indexMap)
+	if len(pruned.Scope) == 0 {
+		t.Fatalf("the pruned indexes have no scope")
+	}
+	if _, ok := pruned.Indexes["M1"]; ok {
+		t.Fatalf("the index of M1 is sent to M2 that does not need it")
+	}
+}
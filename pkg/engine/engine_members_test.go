@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRemoveMember(t *testing.T) {
+	members, engines := prepareTest(3, 1, "line", true, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	waitForCount(t, 3, members, checkPeriod, 2*time.Second)
+
+	if err := engines[2].RemoveMember(members[1].ID()); err != nil {
+		t.Fatal(err)
+	}
+	members[2].Write(newTestItem("David", "Benque"))
+	time.Sleep(20 * syncPeriod)
+	for _, m := range members[:2] {
+		if c := m.GetStore().(*MapStore).Count(); c != 3 {
+			t.Fatalf("%s received data from a removed member: %d items", m.ID(), c)
+		}
+	}
+}
+
+func TestRemoveMemberCancelsRetries(t *testing.T) {
+	errs := make(chan error, 10)
+	members, engines := prepareTest(1, 0, "line", true, time.Hour,
+		WithRetry(RetryPolicy{Attempts: 1, Backoff: 200 * time.Millisecond}),
+		WithErrorHandler(func(err error) { errs <- err }))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	engines[0].connector.RequestKeysChan() <- DataRequest{
+		RequestSource:      members[0].ID(),
+		RequestDestination: "lost",
+		KeyIDPairs:         KeyIDPairs{{ID: "lost", Key: "k"}},
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := engines[0].RemoveMember("lost"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("the request to the removed member was retried: %v", err)
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestLeave(t *testing.T) {
+	members, engines := prepareTest(4, 1, "line", true, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	waitForCount(t, 4, members, checkPeriod, 2*time.Second)
+
+	if err := engines[0].Leave(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for _, e := range engines[1:] {
+		for {
+			if _, ok := e.Departed()[members[0].ID()]; ok {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s did not receive the leave announcement", e.local.ID())
+			}
+			time.Sleep(checkPeriod)
+		}
+	}
+	neighbor := members[1].connector.(*ConnectorImpl).ConnectorCore.(*testConnector)
+	if _, err := neighbor.getRemote(members[0].ID()); err == nil {
+		t.Fatalf("the neighbor is still connected to the departed member")
+	}
+
+	members[0].Write(newTestItem("David", "Benque"))
+	time.Sleep(20 * syncPeriod)
+	for _, m := range members[1:] {
+		if c := m.GetStore().(*MapStore).Count(); c != 4 {
+			t.Fatalf("%s received data from a departed member: %d items", m.ID(), c)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+//merkleHistory is the number of distinct trees kept per owner to answer the IndexRequests of a comparison started on a previous tree
+const merkleHistory = 4
+
+//builtMerkleTree is a MerkleTree advertised with its BuildTime
+type builtMerkleTree struct {
+	buildTime time.Time
+	keys      StampedKeys
+	tree      *MerkleTree
+}
+
+//summarizeIndexes replaces the keys of each Index by the root of their MerkleTree. The trees are kept to answer the IndexRequests
+func (e *Engine) summarizeIndexes(indexMap IndexMap) IndexMap {
+	e.merkleTreesMutex.Lock()
+	defer e.merkleTreesMutex.Unlock()
+	for id, index := range indexMap.Indexes {
+		sort.Sort(index.StampedKeys)
+		history := e.merkleTrees[id]
+		if l := len(history); l > 0 && history[l-1].keys.Equal(index.StampedKeys) {
+			// same content, keep the tree and advertise the latest BuildTime
+			if history[l-1].buildTime.Before(index.BuildTime) {
+				history[l-1].buildTime = index.BuildTime
+			}
+		} else {
+			tree := NewMerkleTree(index.StampedKeys, MerkleDepthOf(len(index.StampedKeys)))
+			history = append(history, builtMerkleTree{buildTime: index.BuildTime, keys: index.StampedKeys, tree: tree})
+			if len(history) > merkleHistory {
+				history = history[len(history)-merkleHistory:]
+			}
+			e.merkleTrees[id] = history
+		}
+		last := history[len(history)-1]
+		indexMap.Indexes[id] = Index{BuildTime: last.buildTime, Mode: IndexModeMerkle, MerkleRoot: last.tree.Root(), MerkleDepth: last.tree.Depth()}
+	}
+	return indexMap
+}
+
+//localMerkleTree returns the tree of the keys of owner id held by the local member, built with depth. It is kept
+//until the cached indexes of the local member change
+func (e *Engine) localMerkleTree(id ID, depth int) *MerkleTree {
+	c := &e.localIndexCache
+	c.Lock()
+	if t, ok := c.merkleTrees[id]; ok && t.Depth() == depth {
+		defer c.Unlock()
+		return t
+	}
+	generation := c.generation
+	c.Unlock()
+	t := NewMerkleTree(e.localIndexes()[id].StampedKeys, depth)
+	c.Lock()
+	defer c.Unlock()
+	if c.generation == generation {
+		if c.merkleTrees == nil {
+			c.merkleTrees = map[ID]*MerkleTree{}
+		}
+		c.merkleTrees[id] = t
+	}
+	return t
+}
+
+//getMerkleTree returns the tree of owner id that has the given root
+func (e *Engine) getMerkleTree(id ID, root Hash) (builtMerkleTree, bool) {
+	e.merkleTreesMutex.RLock()
+	defer e.merkleTreesMutex.RUnlock()
+	history := e.merkleTrees[id]
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].tree.Root() == root {
+			return history[i], true
+		}
+	}
+	return builtMerkleTree{}, false
+}
+
+//answerIndexRequest sends the requested nodes of the trees and the requested deltas of the indexes advertised by the engine
+func (e *Engine) answerIndexRequest(rq IndexRequest) {
+	rs := IndexResponse{
+		RequestSource: rq.RequestSource,
+		Source:        e.local.ID(),
+		BuildTime:     map[ID]time.Time{},
+		MerkleRoots:   map[ID]Hash{},
+		MerkleDepths:  map[ID]int{},
+		MerkleNodes:   map[ID][]MerkleNode{},
+		Deltas:        map[ID]IndexDelta{},
+	}
+	e.addDeltas(rq, &rs)
+	for id, nodes := range rq.MerkleNodes {
+		built, ok := e.getMerkleTree(id, rq.MerkleRoots[id])
+		if !ok {
+			continue // that tree is too old, the requester will start again from a newer index
+		}
+		rs.BuildTime[id] = built.buildTime
+		rs.MerkleRoots[id] = built.tree.Root()
+		rs.MerkleDepths[id] = built.tree.Depth()
+		for _, n := range nodes {
+			if built.tree.Valid(n) {
+				rs.MerkleNodes[id] = append(rs.MerkleNodes[id], built.tree.Node(n))
+			}
+		}
+	}
+	e.sendIndexResponse(rs)
+}
+
+//requestIndex hands the IndexRequest to the connector, or reports a drop with ErrIndexExchangeDropped
+func (e *Engine) requestIndex(rq IndexRequest) {
+	select {
+	case <-e.stopped:
+	default:
+		select {
+		case e.connector.RequestIndexChan() <- rq:
+			return
+		default:
+		}
+	}
+	e.dropExchange(fmt.Errorf("index request to %s: %w", rq.RequestDestination, ErrIndexExchangeDropped))
+}
+
+//sendIndexResponse hands the IndexResponse to the connector, or reports a drop with ErrIndexExchangeDropped
+func (e *Engine) sendIndexResponse(rs IndexResponse) {
+	select {
+	case <-e.stopped:
+	default:
+		select {
+		case e.connector.SendIndexResponseChan() <- rs:
+			return
+		default:
+		}
+	}
+	e.dropExchange(fmt.Errorf("index response to %s: %w", rs.RequestSource, ErrIndexExchangeDropped))
+}
+
+func (e *Engine) dropExchange(err error) {
+	atomic.AddUint64(&e.droppedExchanges, 1)
+	e.errorHandler(err)
+}
+
+//DroppedIndexExchanges returns the number of IndexRequests and IndexResponses that were not sent
+func (e *Engine) DroppedIndexExchanges() uint64 {
+	return atomic.LoadUint64(&e.droppedExchanges)
+}
+
+//processIndexResponse compares the received nodes with the local tree of each owner. It descends into the differing
+//children of internal nodes and computes the keys to fetch and to delete from the differing leaves
+func (e *Engine) processIndexResponse(rs IndexResponse) {
+	currentIndexes := e.localIndexes()
+	needs := e.needs(rs.Source)
+	now := e.clock.Now()
+	e.applyDeltas(rs, currentIndexes, needs, now)
+	next := IndexRequest{
+		RequestSource:      e.local.ID(),
+		RequestDestination: rs.Source,
+		MerkleRoots:        map[ID]Hash{},
+		MerkleNodes:        map[ID][]MerkleNodeID{},
+	}
+	for id, nodes := range rs.MerkleNodes {
+		if !e.syncsOwner(id) {
+			continue
+		}
+		buildTime := rs.BuildTime[id]
+		previous, _ := e.getIndexTime(id)
+		if !previous.Before(buildTime) || e.isStale(id, buildTime) {
+			continue // we have a better version
+		}
+
+		local := e.localMerkleTree(id, rs.MerkleDepths[id])
+		toFetch := KeyIDPairs{}
+		toDelete := KeyIDPairs{}
+		update := StampedKeys{}
+		for _, node := range nodes {
+			if !local.Valid(node.ID) {
+				continue
+			}
+			if node.Leaf {
+				f, d := diffKeys(id, local.Keys(node.ID), node.StampedKeys.unexpired(now), buildTime)
+				toFetch = append(toFetch, f...)
+				toDelete = append(toDelete, d...)
+				update = append(update, node.StampedKeys...)
+				continue
+			}
+			for i, h := range node.Children {
+				c := ChildID(node.ID, i)
+				if !local.Valid(c) || local.Hash(c) == h {
+					continue
+				}
+				if h == (Hash{}) {
+					// the subtree is empty for the owner
+					for _, k := range local.Keys(c) {
+						if k.Timestamp.After(buildTime) {
+							continue // pushed after the build of the owner index
+						}
+						toDelete = append(toDelete, KeyIDPair{ID: id, Key: k.Key})
+					}
+					continue
+				}
+				next.MerkleNodes[id] = append(next.MerkleNodes[id], c)
+			}
+		}
+
+		toFetch = toFetch.filter(needs)
+		if len(next.MerkleNodes[id]) > 0 {
+			// the BuildTime is only recorded once the whole tree has been compared
+			next.MerkleRoots[id] = rs.MerkleRoots[id]
+			if len(toDelete) > 0 {
+				e.delete(toDelete)
+			}
+			continue
+		}
+		if len(toFetch) == 0 && len(toDelete) == 0 {
+			e.updateIndexTime(id, buildTime)
+			continue
+		}
+		e.applyUpdates(rs.Source, id, buildTime, toFetch, toDelete, update)
+	}
+	if len(next.MerkleNodes) > 0 {
+		e.requestIndex(next)
+	}
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//pushSeenSize number of pushes remembered to drop the copies received from other neighbors
+const pushSeenSize = 4096
+
+type pushID struct {
+	origin   ID
+	sequence uint64
+}
+
+//pushSeenSet remembers the last pushSeenSize pushes received by the engine
+type pushSeenSet struct {
+	sync.Mutex
+	seen  map[pushID]struct{}
+	order []pushID
+	next  int
+}
+
+func newPushSeenSet() *pushSeenSet {
+	return &pushSeenSet{
+		seen:  map[pushID]struct{}{},
+		order: make([]pushID, 0, pushSeenSize),
+	}
+}
+
+//add records the push and returns false if it was already seen
+func (s *pushSeenSet) add(id pushID) bool {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.seen[id]; ok {
+		return false
+	}
+	if len(s.order) < pushSeenSize {
+		s.order = append(s.order, id)
+	} else {
+		delete(s.seen, s.order[s.next])
+		s.order[s.next] = id
+		s.next = (s.next + 1) % pushSeenSize
+	}
+	s.seen[id] = struct{}{}
+	return true
+}
+
+//WithPush enables the eager push of the items written by the local member, notified through Connector.Written, and
+//of the items given to Engine.Push. A push is relayed over at most hops links, the periodic index sync still repairs
+//the members that missed it
+func WithPush(hops int) Option {
+	return func(e *Engine) {
+		e.pushHops = hops
+	}
+}
+
+//Push sends to the neighbors the owned items that were just written, without waiting for the next index sync.
+//Items owned by other members are ignored. It does nothing if the engine was not created WithPush. It never blocks:
+//the push is dropped once the engine is stopped or when the queue of the connector is full
+func (e *Engine) Push(items Items) {
+	if e.pushHops <= 0 {
+		return
+	}
+	owned := Items{}
+	for _, i := range items {
+		if i.OwnedBy() == e.local.ID() {
+			owned = append(owned, i)
+		}
+	}
+	if len(owned) == 0 {
+		return
+	}
+	p := DataPush{
+		Origin:   e.local.ID(),
+		Sequence: atomic.AddUint64(&e.pushSequence, 1),
+		Sender:   e.local.ID(),
+		Hops:     e.pushHops,
+		Items:    owned,
+	}
+	e.pushSeen.add(pushID{origin: p.Origin, sequence: p.Sequence})
+	e.sendPush(p)
+}
+
+//sendPush hands the push to the connector, or reports a drop with ErrPushDropped
+func (e *Engine) sendPush(p DataPush) {
+	select {
+	case <-e.stopped:
+	default:
+		select {
+		case e.connector.PushChan() <- p:
+			return
+		default:
+		}
+	}
+	atomic.AddUint64(&e.droppedPushes, 1)
+	e.errorHandler(fmt.Errorf("push %d from %s: %w", p.Sequence, p.Origin, ErrPushDropped))
+}
+
+//DroppedPushes returns the number of pushes that were not sent, the index sync propagates their items
+func (e *Engine) DroppedPushes() uint64 {
+	return atomic.LoadUint64(&e.droppedPushes)
+}
+
+//LeaveHops bounds the number of links crossed by a leave announcement
+var LeaveHops = 16
+
+//Leave announces to the mesh that the local member leaves it, then disconnects the members added to the engine.
+//The announcement is sent before Leave returns, the neighbors disconnect the member when they receive it
+func (e *Engine) Leave() error {
+	p := DataPush{
+		Origin:   e.local.ID(),
+		Sequence: atomic.AddUint64(&e.pushSequence, 1),
+		Sender:   e.local.ID(),
+		Hops:     LeaveHops,
+		Leave:    true,
+	}
+	e.pushSeen.add(pushID{origin: p.Origin, sequence: p.Sequence})
+	errs := []error{e.connector.ProcessDataPush(p)}
+
+	e.membersMutex.Lock()
+	ids := make([]ID, 0, len(e.members))
+	for id := range e.members {
+		ids = append(ids, id)
+	}
+	e.membersMutex.Unlock()
+	for _, id := range ids {
+		errs = append(errs, e.RemoveMember(id))
+	}
+	return errors.Join(errs...)
+}
+
+//Departed returns the members that announced their leave with the time the engine received the announcement
+func (e *Engine) Departed() map[ID]time.Time {
+	e.membersMutex.Lock()
+	defer e.membersMutex.Unlock()
+	departed := make(map[ID]time.Time, len(e.departed))
+	for id, t := range e.departed {
+		departed[id] = t
+	}
+	return departed
+}
+
+//processLeave records the departure and disconnects the member if it is a neighbor
+func (e *Engine) processLeave(p DataPush) {
+	e.membersMutex.Lock()
+	e.departed[p.Origin] = time.Now()
+	e.membersMutex.Unlock()
+	if p.Sender == p.Origin {
+		if err := e.RemoveMember(p.Origin); err != nil {
+			e.errorHandler(err)
+		}
+	}
+}
+
+//processPush stores the pushed items that are newer than the local copies and relays the push
+func (e *Engine) processPush(p DataPush) {
+	if p.Origin == e.local.ID() || !e.pushSeen.add(pushID{origin: p.Origin, sequence: p.Sequence}) {
+		return
+	}
+	if p.Leave {
+		e.processLeave(p)
+		e.relayPush(p)
+		return
+	}
+	kps := KeyIDPairs{}
+	for _, i := range p.Items {
+		if i.OwnedBy() != p.Origin {
+			continue // only the owner can push an item
+		}
+		kps = append(kps, KeyIDPair{ID: p.Origin, Key: i.GetKey()})
+	}
+	current := map[Key]time.Time{}
+	for _, i := range e.local.GetData(kps) {
+		current[i.GetKey()] = i.StampedKey().Timestamp
+	}
+	needs := e.needs(p.Sender)
+	now := e.clock.Now()
+	toPut := Items{}
+	for _, i := range p.Items {
+		if i.OwnedBy() != p.Origin || e.isRetired(p.Origin, i.StampedKey().Timestamp) || i.StampedKey().Expired(now) {
+			continue
+		}
+		if !needs(KeyIDPair{ID: p.Origin, Key: i.GetKey()}) {
+			continue // relayed but not stored
+		}
+		if t, ok := current[i.GetKey()]; ok && !i.StampedKey().Timestamp.After(t) {
+			continue // we have a better version
+		}
+		toPut = append(toPut, i)
+	}
+	e.put(toPut)
+	e.relayPush(p)
+}
+
+//relayPush sends the push to the neighbors if it can cross more links
+func (e *Engine) relayPush(p DataPush) {
+	if p.Hops > 1 {
+		p.Hops--
+		p.Sender = e.local.ID()
+		e.sendPush(p)
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPushLine(t *testing.T) {
+	// the index sync never ticks: only the push can propagate the writes
+	members, engines := prepareTest(NN, 0, "line", true, time.Hour, WithPush(NN))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	item := newTestItem("David", "Benque")
+	members[0].Write(item)
+	waitForCount(t, 1, members, checkPeriod, 2*time.Second)
+
+	item = newTestItem("David", "dbenque")
+	members[0].Write(item)
+	waitForCheck(members, checkPeriod, KeyIDPair{Key: "David", ID: members[0].id},
+		func(i Item) bool {
+			return i != nil && i.(*testItem).Value == "dbenque"
+		}, 2*time.Second)
+	validateSameStore(t, members)
+}
+
+func TestPushHops(t *testing.T) {
+	members, engines := prepareTest(5, 0, "line", true, time.Hour, WithPush(2))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	item := newTestItem("David", "Benque")
+	members[0].Write(item)
+	waitForCount(t, 1, members[:3], checkPeriod, 2*time.Second)
+	time.Sleep(100 * time.Millisecond)
+	for _, m := range members[3:] {
+		if c := m.GetStore().(*MapStore).Count(); c != 0 {
+			t.Fatalf("%s received a push beyond the hop limit", m.ID())
+		}
+	}
+}
+
+func TestPushNotOwned(t *testing.T) {
+	members, engines := prepareTest(2, 0, "line", true, time.Hour, WithPush(2))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	item := newTestItem("David", "Benque")
+	item.Owner = members[1].ID()
+	engines[0].Push(Items{item})
+	time.Sleep(100 * time.Millisecond)
+	if c := members[1].GetStore().(*MapStore).Count(); c != 0 {
+		t.Fatalf("an item not owned by the pushing member was stored")
+	}
+}
+
+func TestPushDropped(t *testing.T) {
+	members, engines := prepareTest(1, 0, "line", true, time.Hour, WithPush(2), WithErrorHandler(func(error) {}))
+	item := newTestItem("David", "Benque")
+	item.Owner = members[0].ID()
+	// nobody consumes the queue of the connector
+	for i := 0; i <= DefaultConnectorQueueSize; i++ {
+		engines[0].Push(Items{item})
+	}
+	if d := engines[0].DroppedPushes(); d != 1 {
+		t.Fatalf("expected 1 push dropped on the full queue, got %d", d)
+	}
+
+	members, engines = prepareTest(1, 0, "line", true, time.Hour, WithPush(2), WithErrorHandler(func(error) {}))
+	item.Owner = members[0].ID()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	engines[0].Run(ctx)
+	engines[0].Push(Items{item})
+	if d := engines[0].DroppedPushes(); d != 1 {
+		t.Fatalf("expected the push of the stopped engine to be dropped, got %d drops", d)
+	}
+}
+
+func TestPushSeenSet(t *testing.T) {
+	s := newPushSeenSet()
+	if !s.add(pushID{origin: "M0", sequence: 0}) {
+		t.Fatalf("first push reported as seen")
+	}
+	if s.add(pushID{origin: "M0", sequence: 0}) {
+		t.Fatalf("second copy of the push not detected")
+	}
+	for n := uint64(1); n <= pushSeenSize; n++ {
+		s.add(pushID{origin: "M0", sequence: n})
+	}
+	if len(s.seen) != pushSeenSize {
+		t.Fatalf("seen set not bounded: %d", len(s.seen))
+	}
+	if !s.add(pushID{origin: "M0", sequence: 0}) {
+		t.Fatalf("the oldest push should have been forgotten")
+	}
+}
+
+func TestDiffKeysKeepsPushedKeys(t *testing.T) {
+	buildTime := time.Now()
+	current := StampedKeys{
+		{Key: "removed", Timestamp: buildTime.Add(-time.Second)},
+		{Key: "pushed", Timestamp: buildTime.Add(time.Second)},
+	}
+	toFetch, toDelete := diffKeys("M0", current, StampedKeys{}, buildTime)
+	if len(toFetch) != 0 {
+		t.Fatalf("unexpected fetch %v", toFetch)
+	}
+	if len(toDelete) != 1 || toDelete[0].Key != "removed" {
+		t.Fatalf("expected to delete only the removed key, got %v", toDelete)
+	}
+}
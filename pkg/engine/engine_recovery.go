@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//ErrRecoveryTimeout is reported when the recovery of the local shard ends before it could be confirmed by all the neighbors
+var ErrRecoveryTimeout = errors.New("recovery timeout")
+
+//recovery tracks the rebuild of the local shard from the mesh
+type recovery struct {
+	sync.Mutex
+	timeout  time.Duration
+	active   bool
+	deadline time.Time
+	heard    map[ID]struct{}
+	best     time.Time // highest BuildTime of the local index advertised by the neighbors
+	done     chan struct{}
+}
+
+func newRecovery() *recovery {
+	return &recovery{
+		heard: map[ID]struct{}{},
+		done:  make(chan struct{}),
+	}
+}
+
+//WithRecovery protects the data of the local member against the loss of its storage: when the local member starts
+//without any owned item, the engine pulls back its shard from the neighbors before publishing its own index.
+//Otherwise the empty index would delete the shard from all the replicas. The recovery ends when all the neighbors
+//were heard and the most recent version they know is restored, or after timeout
+func WithRecovery(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.recovery.timeout = timeout
+	}
+}
+
+//Recovered returns a channel closed once the engine publishes the index of the local member. The local member
+//should not write before: its writes would be mixed with the recovered versions
+func (e *Engine) Recovered() <-chan struct{} {
+	return e.recovery.done
+}
+
+//Recovering returns true while the engine rebuilds the local shard
+func (e *Engine) Recovering() bool {
+	e.recovery.Lock()
+	defer e.recovery.Unlock()
+	return e.recovery.active
+}
+
+//startRecovery enters the recovery if it is enabled and the local shard is empty
+func (e *Engine) startRecovery(now time.Time) {
+	r := e.recovery
+	r.Lock()
+	defer r.Unlock()
+	if r.timeout <= 0 || len(e.local.GetIndexes().Indexes[e.local.ID()].StampedKeys) > 0 {
+		close(r.done)
+		return
+	}
+	r.active = true
+	r.deadline = now.Add(r.timeout)
+}
+
+//recoveryHeard records the version of the local shard known by the source of an IndexMap
+func (e *Engine) recoveryHeard(indexMap IndexMap) {
+	r := e.recovery
+	r.Lock()
+	defer r.Unlock()
+	if !r.active {
+		return
+	}
+	r.heard[indexMap.Source] = struct{}{}
+	if index, ok := indexMap.Indexes[e.local.ID()]; ok && !index.Retired && index.BuildTime.After(r.best) {
+		r.best = index.BuildTime
+	}
+}
+
+//checkRecovery ends the recovery once all the living neighbors were heard and the best version of the local shard
+//is restored, or when the timeout expires
+func (e *Engine) checkRecovery(now time.Time) {
+	r := e.recovery
+	r.Lock()
+	if !r.active {
+		r.Unlock()
+		return
+	}
+	timeout := now.After(r.deadline)
+	if !timeout {
+		if len(r.heard) == 0 {
+			r.Unlock()
+			return
+		}
+		e.membersMutex.Lock()
+		for id := range e.members {
+			if _, ok := r.heard[id]; !ok && !e.isDead(id) {
+				e.membersMutex.Unlock()
+				r.Unlock()
+				return
+			}
+		}
+		e.membersMutex.Unlock()
+		if restored, _ := e.getIndexTime(e.local.ID()); restored.Before(r.best) {
+			r.Unlock()
+			return
+		}
+	}
+	r.active = false
+	// the next BuildTime of the local index must be newer than the recovered one
+	e.clock.Observe(r.best)
+	close(r.done)
+	r.Unlock()
+
+	if timeout {
+		e.errorHandler(fmt.Errorf("%w: the shard of %s may be incomplete", ErrRecoveryTimeout, e.local.ID()))
+	}
+}
+
+//syncsOwner returns true if the indexes of owner id received from the neighbors must be applied. The local shard is
+//only synchronized during the recovery
+func (e *Engine) syncsOwner(id ID) bool {
+	return id != e.local.ID() || e.Recovering()
+}
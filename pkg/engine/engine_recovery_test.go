@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+//restartEmpty stops M0 and starts it again with an empty store, as after the loss of its disk
+func restartEmpty(t *testing.T, members []*testMember, engines []*Engine, stop0 chan struct{}, options ...Option) (*testMember, *Engine) {
+	close(stop0)
+	time.Sleep(2 * syncPeriod)
+	m0 := newTestMember(string(members[0].ID()), NewMapStore())
+	e0 := NewEngine(m0, syncPeriod, options...)
+	if err := engines[1].AddMember(m0); err != nil {
+		t.Fatal(err)
+	}
+	return m0, e0
+}
+
+func TestRecovery(t *testing.T) {
+	testRecovery(t)
+}
+
+func TestRecoveryMerkle(t *testing.T) {
+	testRecovery(t, WithIndexMode(IndexModeMerkle))
+}
+
+func TestRecoveryDelta(t *testing.T) {
+	testRecovery(t, WithIndexMode(IndexModeDelta))
+}
+
+func testRecovery(t *testing.T, options ...Option) {
+	members, engines := prepareTest(3, 3, "line", true, syncPeriod, options...)
+	stop0 := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go engines[0].Run(stopContext(stop0))
+	runEngines(stop, engines[1:])
+	waitForCount(t, 9, members, checkPeriod, 2*time.Second)
+	before := members[0].GetStore().(*MapStore).Dump()
+
+	m0, e0 := restartEmpty(t, members, engines, stop0, append([]Option{WithRecovery(time.Second)}, options...)...)
+	go e0.Run(stopContext(stop))
+	select {
+	case <-e0.Recovered():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the recovery did not end")
+	}
+	if c := len(m0.GetStore().GetIndex(m0.ID()).StampedKeys); c != 3 {
+		t.Fatalf("the recovered member has %d owned items, expected 3", c)
+	}
+	waitForCount(t, 9, []*testMember{m0}, checkPeriod, 2*time.Second)
+	if after := m0.GetStore().(*MapStore).Dump(); after != before {
+		t.Fatalf("the recovered store differs from the lost one:\n%s\ninstead of\n%s", after, before)
+	}
+
+	// the published index of the recovered member deletes nothing
+	m0.Write(newTestItem("David", "Benque"))
+	waitForCount(t, 10, []*testMember{m0, members[1], members[2]}, checkPeriod, 2*time.Second)
+	for _, m := range []*testMember{members[1], members[2]} {
+		if c := len(m.GetStore().GetIndex(m0.ID()).StampedKeys); c != 4 {
+			t.Fatalf("%s holds %d items of the recovered member, expected 4", m.ID(), c)
+		}
+	}
+}
+
+func TestRecoveryNothingToRecover(t *testing.T) {
+	members, engines := prepareTest(2, 0, "line", true, syncPeriod, WithRecovery(time.Hour))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	for _, e := range engines {
+		select {
+		case <-e.Recovered():
+		case <-time.After(time.Second):
+			t.Fatalf("%s is still recovering", e.local.ID())
+		}
+	}
+	members[0].Write(newTestItem("David", "Benque"))
+	waitForCount(t, 1, members, checkPeriod, time.Second)
+	if i := members[1].GetStore().Get(KeyIDPair{ID: members[0].ID(), Key: "David"}); i == nil || i.(*testItem).Value != "Benque" {
+		t.Fatalf("the item of M0 did not reach M1, got %v", i)
+	}
+}
+
+func TestRecoveryTimeout(t *testing.T) {
+	errs := make(chan error, 1)
+	_, engines := prepareTest(1, 0, "line", true, syncPeriod, WithRecovery(5*syncPeriod),
+		WithErrorHandler(func(err error) { errs <- err }))
+	engines[0].AddMember(newTestMember("silent", NewMapStore()))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrRecoveryTimeout) {
+			t.Fatalf("expected ErrRecoveryTimeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the recovery did not time out")
+	}
+	if engines[0].Recovering() {
+		t.Fatalf("the engine is still recovering after the timeout")
+	}
+}
+
+func TestRecoveryDeduplicated(t *testing.T) {
+	m0 := newTestMember("M0", NewMapStore())
+	e0 := NewEngine(m0, time.Hour, WithRecovery(time.Hour), WithFetchTimeout(time.Minute))
+	for _, id := range []string{"A", "B"} {
+		e0.AddMember(newTestMember(id, NewMapStore()))
+	}
+	e0.startRecovery(time.Now())
+	now := time.Now()
+	david := StampedKey{Key: "David", Timestamp: now}
+	advertise(e0, "A", now, StampedKeys{david})
+	rqA := nextDataRequest(t, m0)
+	// B knows a newer version: David is already in flight, only Benque is requested, without BuildTime
+	advertise(e0, "B", now.Add(time.Second), StampedKeys{david, {Key: "Benque", Timestamp: now}})
+	rqB := nextDataRequest(t, m0)
+	if len(rqB.KeyIDPairs) != 1 || rqB.AssociatedBuildTime != nil {
+		t.Fatalf("unexpected request %+v", rqB)
+	}
+
+	item := func(key Key) Item {
+		i := newTestItem(key, "v")
+		i.Owner = "M0"
+		i.Time = now
+		return i
+	}
+	e0.receiveData(DataResponse{Items: Items{item("Benque")}})
+	if !e0.Recovering() {
+		t.Fatalf("the recovery ended with David in flight")
+	}
+	e0.receiveData(DataResponse{Items: Items{item("David")}, AssociatedBuildTime: rqA.AssociatedBuildTime})
+	if e0.Recovering() {
+		t.Fatalf("the recovery did not end once the version of B was received")
+	}
+	if restored, _ := e0.getIndexTime("M0"); !restored.Equal(now.Add(time.Second)) {
+		t.Fatalf("the restored version is %s", restored)
+	}
+}
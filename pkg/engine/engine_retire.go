@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"time"
+)
+
+//TombstoneTTL is how long a retired owner is advertised to the mesh. Past it, the BuildTime kept by the engine
+//is enough to ignore the stale indexes of the owner
+var TombstoneTTL = 24 * time.Hour
+
+//tombstone marks a retired owner
+type tombstone struct {
+	buildTime time.Time // last BuildTime of the owner covered by the retirement
+	since     time.Time // when the engine learnt the retirement
+}
+
+//ownerProgress tracks when the BuildTime of an owner last changed
+type ownerProgress struct {
+	buildTime time.Time
+	at        time.Time
+}
+
+//WithOwnerExpiry retires the owners whose BuildTime did not progress for expiry. The engine refreshes the BuildTime
+//of its own index every expiry/4 even without change, so all the members of the mesh must use the same expiry
+func WithOwnerExpiry(expiry time.Duration) Option {
+	return func(e *Engine) {
+		e.ownerExpiry = expiry
+	}
+}
+
+//Retire announces that the local member leaves the mesh for good: its data is purged from the local member
+//and from all the replicas. It is announced with the next indexes, the member must keep running until they are sent
+//and must not write after Retire
+func (e *Engine) Retire() {
+	e.retireOwner(e.local.ID(), e.clock.Now())
+}
+
+//Retired returns the owners currently known as retired with the last BuildTime covered by the retirement
+func (e *Engine) Retired() map[ID]time.Time {
+	e.retiredMutex.RLock()
+	defer e.retiredMutex.RUnlock()
+	retired := make(map[ID]time.Time, len(e.retired))
+	for id, t := range e.retired {
+		retired[id] = t.buildTime
+	}
+	return retired
+}
+
+//retireOwner records the tombstone of owner id and purges its data
+func (e *Engine) retireOwner(id ID, buildTime time.Time) {
+	e.retiredMutex.Lock()
+	if t, ok := e.retired[id]; ok && !buildTime.After(t.buildTime) {
+		e.retiredMutex.Unlock()
+		return
+	}
+	e.retired[id] = tombstone{buildTime: buildTime, since: time.Now()}
+	e.retiredMutex.Unlock()
+
+	e.purgeOwner(id)
+	e.updateIndexTime(id, buildTime)
+	e.merkleTreesMutex.Lock()
+	delete(e.merkleTrees, id)
+	e.merkleTreesMutex.Unlock()
+	e.indexHistoriesMutex.Lock()
+	delete(e.indexHistories, id)
+	e.indexHistoriesMutex.Unlock()
+}
+
+//isRetired returns true if the version of owner id at buildTime is covered by a retirement
+func (e *Engine) isRetired(id ID, buildTime time.Time) bool {
+	e.retiredMutex.RLock()
+	defer e.retiredMutex.RUnlock()
+	t, ok := e.retired[id]
+	return ok && !buildTime.After(t.buildTime)
+}
+
+//unretire drops the tombstone of an owner that published a version newer than its retirement
+func (e *Engine) unretire(id ID) {
+	e.retiredMutex.Lock()
+	defer e.retiredMutex.Unlock()
+	delete(e.retired, id)
+}
+
+//dropRetired removes the items covered by a retirement, they may come from requests issued before it
+func (e *Engine) dropRetired(items Items) Items {
+	kept := make(Items, 0, len(items))
+	for _, i := range items {
+		if e.isRetired(i.OwnedBy(), i.StampedKey().Timestamp) {
+			continue
+		}
+		kept = append(kept, i)
+	}
+	return kept
+}
+
+//expireOwners retires the owners without progress for ownerExpiry and drops the tombstones older than TombstoneTTL
+func (e *Engine) expireOwners(indexMap IndexMap, now time.Time) {
+	if e.ownerExpiry > 0 {
+		for id, index := range indexMap.Indexes {
+			if id == e.local.ID() {
+				continue
+			}
+			p, ok := e.ownerProgress[id]
+			if !ok || !p.buildTime.Equal(index.BuildTime) {
+				e.ownerProgress[id] = ownerProgress{buildTime: index.BuildTime, at: now}
+				continue
+			}
+			if now.Sub(p.at) > e.ownerExpiry {
+				delete(e.ownerProgress, id)
+				delete(indexMap.Indexes, id) // replaced by the tombstone
+				e.retireOwner(id, index.BuildTime)
+			}
+		}
+	}
+
+	e.retiredMutex.Lock()
+	defer e.retiredMutex.Unlock()
+	for id, t := range e.retired {
+		if now.Sub(t.since) > TombstoneTTL {
+			delete(e.retired, id)
+		}
+	}
+}
+
+//addTombstones advertises the retired owners in the IndexMap
+func (e *Engine) addTombstones(indexMap IndexMap) IndexMap {
+	e.retiredMutex.RLock()
+	defer e.retiredMutex.RUnlock()
+	for id, t := range e.retired {
+		indexMap.Indexes[id] = Index{BuildTime: t.buildTime, Mode: e.indexMode, Retired: true}
+	}
+	return indexMap
+}
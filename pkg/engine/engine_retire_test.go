@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetire(t *testing.T) {
+	members, engines := prepareTest(4, 2, "line", false, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	waitForCount(t, 8, members, checkPeriod, 2*time.Second)
+
+	engines[0].Retire()
+	waitForCount(t, 6, members, checkPeriod, 2*time.Second)
+	for _, m := range members {
+		for _, id := range m.GetStore().GetMembers() {
+			if id == members[0].ID() {
+				t.Fatalf("%s still lists the shard of the retired owner", m.ID())
+			}
+		}
+	}
+	for _, e := range engines[1:] {
+		if _, ok := e.Retired()[members[0].ID()]; !ok {
+			t.Fatalf("%s does not know the retirement", e.local.ID())
+		}
+	}
+	// the stale replicas do not bring the data back
+	time.Sleep(20 * syncPeriod)
+	for _, m := range members {
+		if c := m.GetStore().(*MapStore).Count(); c != 6 {
+			t.Fatalf("%s has %d items after the retirement", m.ID(), c)
+		}
+	}
+}
+
+func TestOwnerExpiry(t *testing.T) {
+	expiry := 300 * time.Millisecond
+	members, engines := prepareTest(3, 2, "line", false, syncPeriod, WithOwnerExpiry(expiry))
+	stop := make(chan struct{})
+	defer close(stop)
+	stop0 := make(chan struct{})
+	go engines[0].Run(stopContext(stop0))
+	runEngines(stop, engines[1:])
+	waitForCount(t, 6, members, checkPeriod, 2*time.Second)
+
+	// a running owner refreshes its BuildTime even without change
+	time.Sleep(2 * expiry)
+	for _, m := range members {
+		if c := m.GetStore().(*MapStore).Count(); c != 6 {
+			t.Fatalf("%s expired a running owner: %d items", m.ID(), c)
+		}
+	}
+
+	close(stop0)
+	waitForCount(t, 4, members[1:], checkPeriod, 5*expiry)
+	for _, e := range engines[1:] {
+		if _, ok := e.Retired()[members[0].ID()]; !ok {
+			t.Fatalf("%s did not retire the stopped owner", e.local.ID())
+		}
+	}
+}
+
+func TestRetiredOwnerComesBack(t *testing.T) {
+	e := NewEngine(newTestMember("M1", NewMapStore()), time.Hour)
+	retiredAt := time.Now()
+	e.retireOwner("M0", retiredAt)
+	if !e.isRetired("M0", retiredAt.Add(-time.Second)) {
+		t.Fatalf("the versions before the retirement must be covered")
+	}
+	e.CheckAndGetUpdates(IndexMap{Source: "M2", Indexes: map[ID]Index{"M0": {BuildTime: retiredAt.Add(-time.Second)}}})
+	if _, ok := e.Retired()["M0"]; !ok {
+		t.Fatalf("a stale index must not cancel the retirement")
+	}
+	e.CheckAndGetUpdates(IndexMap{Source: "M2", Indexes: map[ID]Index{"M0": {BuildTime: retiredAt.Add(time.Second)}}})
+	if _, ok := e.Retired()["M0"]; ok {
+		t.Fatalf("a newer index of the owner must cancel the retirement")
+	}
+}
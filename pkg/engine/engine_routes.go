@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"time"
+)
+
+//route is the neighbor through which a member is reached. seen is the freshest version of the member, BuildTime of
+//its index or version of its interest, advertised by that neighbor: the neighbor closest to the member hears it first
+type route struct {
+	via  ID
+	seen time.Time
+}
+
+//heardRoutes learns the routes advertised by the IndexMap: its Source is a neighbor, and the next hop toward the
+//owners of its indexes and the members of its interests. A member that Source learnt from the local member is not
+//reached through Source. An IndexMap whose Source is not a peer of the connector teaches no route: the local
+//member can't send anything to it
+func (e *Engine) heardRoutes(indexMap IndexMap) {
+	if !e.isPeer(indexMap.Source) {
+		return
+	}
+	e.routesMutex.Lock()
+	defer e.routesMutex.Unlock()
+	e.routes[indexMap.Source] = route{via: indexMap.Source, seen: indexMap.Clock}
+	for id, index := range indexMap.Indexes {
+		e.learnRoute(id, indexMap.Source, index.BuildTime)
+	}
+	for id, mi := range indexMap.Interests {
+		if mi.Via != e.local.ID() {
+			e.learnRoute(id, indexMap.Source, mi.Version)
+		}
+	}
+}
+
+//isPeer returns true if the connector is connected to the member id
+func (e *Engine) isPeer(id ID) bool {
+	for _, p := range e.connector.Peers() {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+//learnRoute keeps via as the next hop toward id if it advertises a fresher version, or if the current next hop is dead
+func (e *Engine) learnRoute(id ID, via ID, seen time.Time) {
+	if id == e.local.ID() || id == via {
+		return
+	}
+	r, ok := e.routes[id]
+	switch {
+	case ok && r.via == id:
+		return // a neighbor is reached directly
+	case !ok, r.via == via, seen.After(r.seen), e.isDead(r.via):
+		if ok && r.seen.After(seen) {
+			seen = r.seen
+		}
+		e.routes[id] = route{via: via, seen: seen}
+	}
+}
+
+//forgetRoutes drops the routes toward and through the member id
+func (e *Engine) forgetRoutes(id ID) {
+	e.routesMutex.Lock()
+	defer e.routesMutex.Unlock()
+	for dest, r := range e.routes {
+		if dest == id || r.via == id {
+			delete(e.routes, dest)
+		}
+	}
+}
+
+//NextHop returns the neighbor to which a message for destination is sent, false if no live neighbor leads to it
+func (e *Engine) NextHop(destination ID) (ID, bool) {
+	e.membersMutex.Lock()
+	_, neighbor := e.members[destination]
+	e.membersMutex.Unlock()
+	if neighbor {
+		return destination, true
+	}
+	e.routesMutex.RLock()
+	r, ok := e.routes[destination]
+	e.routesMutex.RUnlock()
+	if !ok || e.isDead(r.via) {
+		return "", false
+	}
+	return r.via, true
+}
+
+//Routes returns the next hop toward each member known by the engine
+func (e *Engine) Routes() map[ID]ID {
+	e.routesMutex.RLock()
+	defer e.routesMutex.RUnlock()
+	routes := make(map[ID]ID, len(e.routes))
+	for id, r := range e.routes {
+		routes[id] = r.via
+	}
+	return routes
+}
@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+//staticRouter routes the members listed, the others are not reachable
+type staticRouter map[ID]ID
+
+func (r staticRouter) NextHop(destination ID) (ID, bool) {
+	hop, ok := r[destination]
+	return hop, ok
+}
+
+func TestRoutes(t *testing.T) {
+	m := newTestMember("M3", NewMapStore())
+	e := NewEngine(m, time.Hour)
+	for _, id := range []string{"M2", "M5"} {
+		e.AddMember(newTestMember(id, NewMapStore()))
+	}
+	now := time.Now()
+
+	// a member that is not a peer can't be a next hop
+	e.CheckAndGetUpdates(IndexMap{Source: "M6", Indexes: map[ID]Index{"M0": {BuildTime: now}}})
+	if routes := e.Routes(); len(routes) != 0 {
+		t.Fatalf("routes learnt from a member that is not a peer: %v", routes)
+	}
+
+	e.CheckAndGetUpdates(IndexMap{
+		Source:    "M2",
+		Indexes:   map[ID]Index{"M0": {BuildTime: now}, "M1": {BuildTime: now}},
+		Interests: map[ID]MemberInterest{"M1": {Version: now, Via: "M1"}, "M4": {Version: now, Via: "M3"}},
+	})
+	routes := e.Routes()
+	if len(routes) != 3 || routes["M2"] != "M2" || routes["M0"] != "M2" || routes["M1"] != "M2" {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+
+	// M5 advertises a fresher version of M0 only
+	e.CheckAndGetUpdates(IndexMap{
+		Source:  "M5",
+		Indexes: map[ID]Index{"M0": {BuildTime: now.Add(time.Second)}, "M1": {BuildTime: now.Add(-time.Second)}},
+	})
+	if hop, _ := e.NextHop("M0"); hop != "M5" {
+		t.Fatalf("expected M0 through M5, got %s", hop)
+	}
+	if hop, _ := e.NextHop("M1"); hop != "M2" {
+		t.Fatalf("expected M1 through M2, got %s", hop)
+	}
+
+	e.RemoveMember("M5")
+	if hop, ok := e.NextHop("M0"); ok {
+		t.Fatalf("the route through the removed member was kept: %s", hop)
+	}
+	if _, ok := e.NextHop("M4"); ok {
+		t.Fatalf("M4 was learnt back from the local member")
+	}
+}
+
+func TestRoutesLine(t *testing.T) {
+	_, engines := prepareTest(4, 0, "line", false, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	waitFor(t, "the routes", func() bool {
+		hop, ok := engines[3].NextHop("M0")
+		return ok && hop == "M2"
+	})
+	if hop, _ := engines[3].NextHop("M2"); hop != "M2" {
+		t.Fatalf("a neighbor is reached directly, got %s", hop)
+	}
+}
+
+func TestRoutedDataRequest(t *testing.T) {
+	members, _ := prepareTest(3, 0, "line", false, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, m := range members {
+		// each member of the loop holds a slot of the next hop until the request is given up
+		WithPeerInflight(MaxRouteHops)(m.connector.(*ConnectorImpl))
+		go m.connector.Run(ctx)
+	}
+	item := newTestItem("David", "Benque")
+	members[0].Write(item)
+	kp := KeyIDPair{ID: members[0].ID(), Key: "David"}
+	impl := members[2].connector.(*ConnectorImpl)
+
+	// M2 is not a neighbor of M0: the request is relayed by M1, which reaches M0 directly
+	members[2].connector.SetRouter(staticRouter{"M0": "M1"})
+	impl.RequestKeysCh <- DataRequest{RequestSource: "M2", RequestDestination: "M0", KeyIDPairs: KeyIDPairs{kp}}
+	select {
+	case rs := <-impl.ReceiveDataCh:
+		if len(rs.Items) != 1 || rs.Items[0].GetKey() != "David" {
+			t.Fatalf("unexpected response %v", rs.Items)
+		}
+	case err := <-impl.ErrorChan():
+		t.Fatalf("the request failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("no response")
+	}
+
+	// a routing loop is given up
+	members[1].connector.SetRouter(staticRouter{"M9": "M2"})
+	members[2].connector.SetRouter(staticRouter{"M9": "M1"})
+	impl.RequestKeysCh <- DataRequest{RequestSource: "M2", RequestDestination: "M9", KeyIDPairs: KeyIDPairs{kp}}
+	select {
+	case err := <-impl.ErrorChan():
+		if !errors.Is(err, ErrNoRoute) {
+			t.Fatalf("expected ErrNoRoute, got %v", err)
+		}
+	case <-impl.ReceiveDataCh:
+		t.Fatalf("unexpected response")
+	case <-time.After(time.Second):
+		t.Fatalf("the loop was not reported")
+	}
+}
+
+func TestFetchRoutedToOwner(t *testing.T) {
+	m := newTestMember("M2", NewMapStore())
+	e := NewEngine(m, time.Hour, WithRetry(RetryPolicy{}), WithErrorHandler(func(error) {}))
+	e.AddMember(newTestMember("M1", NewMapStore()))
+	now := time.Now()
+	advertise(e, "M1", now, StampedKeys{{Key: "David", Timestamp: now}})
+
+	// the keys are requested to the neighbor that advertised them, not through the route toward their owner
+	rq := nextDataRequest(t, m)
+	if rq.RequestDestination != "M1" {
+		t.Fatalf("the fetch was not sent to the neighbor that advertised it: %+v", rq)
+	}
+	// the route is the fallback once that neighbor failed
+	e.handleError(nil, &sync.WaitGroup{}, &ConnectorError{Op: "ForwardDataRequest", Request: &rq, Err: errors.New("boom")})
+	if rq := nextDataRequest(t, m); rq.RequestDestination != "M0" {
+		t.Fatalf("the fetch was not requested to the owner: %+v", rq)
+	}
+	if hop, ok := e.NextHop("M0"); !ok || hop != "M1" {
+		t.Fatalf("expected the route through M1, got %s", hop)
+	}
+}
+
+func TestServeDataRequestRelay(t *testing.T) {
+	m := newTestMember("M1", NewMapStore(), WithPeerInflight(1))
+	impl := m.connector.(*ConnectorImpl)
+	entered := make(chan struct{})
+	relay := func(ctx context.Context, hop ID, rq DataRequest) (Items, error) {
+		close(entered)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	rq := DataRequest{RequestSource: "M0", RequestDestination: "M2"}
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := impl.ServeDataRequest(ctx, rq, relay)
+		errs <- err
+	}()
+	<-entered
+	if _, err := impl.ServeDataRequest(context.Background(), rq, relay); !errors.Is(err, ErrPeerBusy) {
+		t.Fatalf("expected ErrPeerBusy beyond the requests in flight toward M2, got %v", err)
+	}
+	// the relay is abandoned with the incoming request
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the relay to be canceled, got %v", err)
+	}
+	if m := impl.Metrics().Requests["M2"]; m.Depth != 0 || m.Dropped != 1 || m.Sent != 1 {
+		t.Fatalf("unexpected metrics of M2 %+v", m)
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	m := newTestMember("M1", NewMapStore())
+	impl := m.connector.(*ConnectorImpl)
+	impl.SetRouter(staticRouter{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go impl.Run(ctx)
+
+	// the request is not sent to its destination, which is not a neighbor
+	impl.RequestKeysCh <- DataRequest{RequestSource: "M1", RequestDestination: "M9"}
+	select {
+	case err := <-impl.ErrorChan():
+		var cerr *ConnectorError
+		if !errors.As(err, &cerr) || !errors.Is(err, ErrNoRoute) || cerr.Request == nil {
+			t.Fatalf("expected ErrNoRoute with the request, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the missing route was not reported")
+	}
+	if _, ok := impl.Metrics().Requests["M9"]; ok {
+		t.Fatalf("a slot of M9 was taken")
+	}
+
+	relay := func(ctx context.Context, hop ID, rq DataRequest) (Items, error) {
+		t.Fatalf("the request was relayed to %s", hop)
+		return nil, nil
+	}
+	if _, err := impl.ServeDataRequest(context.Background(), DataRequest{RequestSource: "M0", RequestDestination: "M9"}, relay); !errors.Is(err, ErrNoRoute) {
+		t.Fatalf("expected ErrNoRoute, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//syncJitter is the largest fraction removed at random from an adaptive period, so that the members of a quiet
+//mesh don't send their indexes in lockstep
+const syncJitter = 0.2
+
+//syncScheduler chooses the period of the next IndexMap. It goes back to min as soon as a change is seen and
+//doubles up to max at each quiet period. With min == max the period is fixed, without jitter
+type syncScheduler struct {
+	sync.Mutex
+	min, max time.Duration
+	current  time.Duration
+	dirty    bool
+	random   *rand.Rand
+	wake     chan struct{}
+}
+
+func newSyncScheduler(period, min, max time.Duration) *syncScheduler {
+	return &syncScheduler{
+		min:     min,
+		max:     max,
+		current: period,
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+//WithAdaptiveSync lets the engine adapt its sync period between min and max: it is shortened to min when the local
+//indexes or the ones of the neighbors change, and doubled at each quiet period up to max. The neighbors are expected
+//at least every max period by the failure detector, so all the members of the mesh must use the same max
+func WithAdaptiveSync(min, max time.Duration) Option {
+	return func(e *Engine) {
+		e.scheduler = newSyncScheduler(e.syncPeriod, min, max)
+		e.failureDetector.floor = max
+	}
+}
+
+//changed shortens the period: the next one is min. The sync loop is woken up if it waits for a longer period
+func (s *syncScheduler) changed() {
+	s.Lock()
+	defer s.Unlock()
+	s.dirty = true
+	if s.current > s.min {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+//next returns the time to wait before the next IndexMap
+func (s *syncScheduler) next() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	switch {
+	case s.dirty:
+		s.current = s.min
+		s.dirty = false
+	case s.current < s.max:
+		s.current *= 2
+	}
+	if s.current > s.max {
+		s.current = s.max
+	}
+	if s.current < s.min {
+		s.current = s.min
+	}
+	if s.min == s.max {
+		return s.current
+	}
+	return s.current - time.Duration(s.random.Float64()*syncJitter*float64(s.current))
+}
+
+//period returns the current period, without jitter
+func (s *syncScheduler) period() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	return s.current
+}
+
+//SyncPeriod returns the current period between two IndexMaps sent by the engine
+func (e *Engine) SyncPeriod() time.Duration {
+	return e.scheduler.period()
+}
+
+//resetTimer waits d on the timer, whether it fired or not
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncScheduler(t *testing.T) {
+	s := newSyncScheduler(10*time.Millisecond, 10*time.Millisecond, 80*time.Millisecond)
+	for _, expected := range []time.Duration{20, 40, 80, 80} {
+		expected *= time.Millisecond
+		d := s.next()
+		if s.period() != expected {
+			t.Fatalf("expected a period of %v, got %v", expected, s.period())
+		}
+		if d > expected || d < expected-time.Duration(syncJitter*float64(expected)) {
+			t.Fatalf("the jitter of %v is out of bounds: %v", expected, d)
+		}
+	}
+
+	s.changed()
+	select {
+	case <-s.wake:
+	default:
+		t.Fatalf("the sync loop was not woken up")
+	}
+	if s.next(); s.period() != 10*time.Millisecond {
+		t.Fatalf("the period was not shortened: %v", s.period())
+	}
+}
+
+func TestSyncSchedulerFixed(t *testing.T) {
+	s := newSyncScheduler(syncPeriod, syncPeriod, syncPeriod)
+	s.changed()
+	if len(s.wake) != 0 {
+		t.Fatalf("a fixed period does not wake up the sync loop")
+	}
+	for i := 0; i < 3; i++ {
+		if d := s.next(); d != syncPeriod {
+			t.Fatalf("expected the fixed period %v, got %v", syncPeriod, d)
+		}
+	}
+}
+
+func TestAdaptiveSync(t *testing.T) {
+	max := 8 * syncPeriod
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod, WithAdaptiveSync(syncPeriod, max))
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	quiet := func() bool {
+		for _, e := range engines {
+			if e.SyncPeriod() != max {
+				return false
+			}
+		}
+		return true
+	}
+	waitFor(t, "the quiet mesh", quiet)
+	// the neighbors sending at the max period are not suspected
+	time.Sleep(4 * max)
+	for _, e := range engines {
+		for id, s := range e.Peers() {
+			if s != PeerAlive {
+				t.Fatalf("%s is %s for %s", id, s, e.local.ID())
+			}
+		}
+	}
+
+	members[0].Write(newTestItem("David", "Benque"))
+	waitForCount(t, 1, members, checkPeriod, 2*time.Second)
+	for _, m := range members {
+		if m.GetStore().Get(KeyIDPair{ID: members[0].ID(), Key: "David"}) == nil {
+			t.Fatalf("%s did not get the write", m.ID())
+		}
+	}
+	waitFor(t, "the quiet mesh after the write", quiet)
+}
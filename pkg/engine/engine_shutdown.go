@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//ErrShutdownTimeout is returned by Run when the goroutines of the engine or of the connector did not stop in time
+var ErrShutdownTimeout = errors.New("shutdown timeout")
+
+//ErrAlreadyRun is returned by Run when the engine was already run: an Engine runs once
+var ErrAlreadyRun = errors.New("engine already run")
+
+//DefaultShutdownTimeout bounds each step of the shutdown of an engine
+const DefaultShutdownTimeout = 10 * time.Second
+
+//WithShutdownTimeout bounds the time given to each step of the shutdown: the stop of the loops of the engine, and the
+//drain of the operations of the connector in flight. Default is DefaultShutdownTimeout
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.shutdownTimeout = timeout
+	}
+}
+
+//WithFinalPush sends a last IndexMap to the neighbors when Run stops, so that they see the latest writes of the
+//local member without waiting for another sync
+func WithFinalPush() Option {
+	return func(e *Engine) {
+		e.finalPush = true
+	}
+}
+
+//shutdown waits for the loops of the engine, sends the final IndexMap, then stops the connector and waits for the
+//operations in flight
+func (e *Engine) shutdown(loops *sync.WaitGroup, cancelConnector context.CancelFunc, connectorDone <-chan struct{}) error {
+	loopsDone := make(chan struct{})
+	go func() {
+		loops.Wait()
+		close(loopsDone)
+	}()
+	if !waitDone(loopsDone, e.shutdownTimeout) {
+		cancelConnector()
+		return fmt.Errorf("engine loops: %w", ErrShutdownTimeout)
+	}
+	var err error
+	if e.finalPush {
+		// sent by the connector core directly: the dispatch loop would drop it when it stops
+		if perr := e.connector.ProcessIndexMap(e.buildIndexMap()); perr != nil {
+			err = fmt.Errorf("final push: %w", perr)
+		}
+	}
+	cancelConnector()
+	if !waitDone(connectorDone, e.shutdownTimeout) {
+		return errors.Join(err, fmt.Errorf("connector: %w", ErrShutdownTimeout))
+	}
+	return err
+}
+
+//waitDone returns false if done is not closed within timeout
+func waitDone(done <-chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
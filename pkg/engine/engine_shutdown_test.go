@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func runContext(e *Engine) (context.CancelFunc, <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- e.Run(ctx) }()
+	return cancel, errs
+}
+
+func waitRun(t *testing.T, errs <-chan error) {
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("unexpected shutdown error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Run did not return")
+	}
+}
+
+func TestRunShutdown(t *testing.T) {
+	members, engines := prepareTest(3, 5, "line", true, syncPeriod)
+	cancels := []context.CancelFunc{}
+	errs := []<-chan error{}
+	for _, e := range engines {
+		cancel, err := runContext(e)
+		cancels = append(cancels, cancel)
+		errs = append(errs, err)
+	}
+	waitForCount(t, 15, members, checkPeriod, 2*time.Second)
+	for i := range engines {
+		cancels[i]()
+		waitRun(t, errs[i])
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	_, engines := prepareTest(1, 0, "line", true, syncPeriod)
+	cancel, errs := runContext(engines[0])
+	cancel()
+	waitRun(t, errs)
+	if err := engines[0].Run(context.Background()); !errors.Is(err, ErrAlreadyRun) {
+		t.Fatalf("expected ErrAlreadyRun, got %v", err)
+	}
+}
+
+func TestRunFinalPush(t *testing.T) {
+	// no sync during the test: only the final push can advertise the write
+	members, engines := prepareTest(2, 0, "line", true, time.Hour, WithFinalPush())
+	cancel0, errs0 := runContext(engines[0])
+	cancel1, errs1 := runContext(engines[1])
+	defer func() {
+		cancel1()
+		waitRun(t, errs1)
+	}()
+
+	members[0].Write(newTestItem("David", "Benque"))
+	owner := members[0].ID()
+	expected := members[0].GetStore().GetIndex(owner).StampedKeys.Digest()
+	cancel0()
+	waitRun(t, errs0)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if d, ok := engines[1].PeerDigest(owner, owner); ok && d.Hash == expected {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the final IndexMap was not received")
+		}
+		time.Sleep(checkPeriod)
+	}
+}
+
+func TestIndexExchangeDropped(t *testing.T) {
+	errs := []error{}
+	members, engines := prepareTest(1, 0, "line", true, time.Hour, WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	e := engines[0]
+	// nobody consumes the queues of the connector
+	for i := 0; i <= DefaultConnectorQueueSize; i++ {
+		e.requestIndex(IndexRequest{RequestSource: members[0].ID(), RequestDestination: "M1"})
+		e.answerIndexRequest(IndexRequest{RequestSource: "M1", RequestDestination: members[0].ID()})
+	}
+	if d := e.DroppedIndexExchanges(); d != 2 {
+		t.Fatalf("expected 2 exchanges dropped on the full queues, got %d", d)
+	}
+
+	// a stopped engine never blocks
+	close(e.stopped)
+	done := make(chan struct{})
+	go func() {
+		e.requestIndex(IndexRequest{RequestSource: members[0].ID(), RequestDestination: "M1"})
+		e.answerIndexRequest(IndexRequest{RequestSource: "M1", RequestDestination: members[0].ID()})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("the stopped engine is blocked on the full queues of the connector")
+	}
+	if len(errs) != 4 || !errors.Is(errs[3], ErrIndexExchangeDropped) {
+		t.Fatalf("expected 4 drops reported with ErrIndexExchangeDropped, got %v", errs)
+	}
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -29,23 +30,41 @@ func BenchmarkAddLine(b *testing.B) {
 	b.ResetTimer()
 	stop := make(chan struct{})
 	runEngines(stop, engines)
-	waitForCount(D*N, members, checkPeriod, 60*time.Second)
+	waitForCount(b, D*N, members, checkPeriod, 60*time.Second)
 	close(stop)
 }
 
 func runEngines(stop chan struct{}, engines []*Engine) {
 	for _, e := range engines {
-		go e.Run(stop)
+		go e.Run(stopContext(stop))
 	}
 
 }
-func waitForCount(count int, members []*testMember, checkPeriod time.Duration, timeout time.Duration) {
+
+//stopContext returns a context canceled when stop is closed
+func stopContext(stop <-chan struct{}) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+	return ctx
+}
+
+//waitForCount waits until each member holds count items, it fails the test if one does not within timeout
+func waitForCount(t testing.TB, count int, members []*testMember, checkPeriod time.Duration, timeout time.Duration) {
+	t.Helper()
 	var wg sync.WaitGroup
 	for i := 0; i < len(members); i++ {
 		s := members[i].GetStore().(*MapStore)
 		s.UntilCount(&wg, count, checkPeriod, timeout)
 	}
 	wg.Wait()
+	for _, m := range members {
+		if n := m.GetStore().(*MapStore).Count(); n != count {
+			t.Fatalf("waitForCount: %s holds %d items instead of %d after %s", m.ID(), n, count, timeout)
+		}
+	}
 }
 func waitForCheck(members []*testMember, checkPeriod time.Duration, kp KeyIDPair, check func(i Item) bool, timeout time.Duration) {
 	var wg sync.WaitGroup
@@ -56,13 +75,13 @@ func waitForCheck(members []*testMember, checkPeriod time.Duration, kp KeyIDPair
 	wg.Wait()
 }
 
-func prepareTest(N int, D int, meshType string, panicOnDelete bool, syncPeriod time.Duration) ([]*testMember, []*Engine) {
+func prepareTest(N int, D int, meshType string, panicOnDelete bool, syncPeriod time.Duration, options ...Option) ([]*testMember, []*Engine) {
 	members := make([]*testMember, N)
 	engines := make([]*Engine, N)
 
 	for i := range members {
 		members[i] = newTestMember(fmt.Sprintf("M%d", i), NewMapStore())
-		engines[i] = NewEngine(members[i], syncPeriod)
+		engines[i] = NewEngine(members[i], syncPeriod, options...)
 		if panicOnDelete {
 			members[i].GetStore().(*MapStore).PanicOnDelete()
 		}
@@ -150,10 +169,10 @@ func prepareTest(N int, D int, meshType string, panicOnDelete bool, syncPeriod t
 func addOnlySequence(t *testing.T, members []*testMember, engines []*Engine) {
 	stop := make(chan struct{})
 	runEngines(stop, engines)
-	waitForCount(DD*NN, members, checkPeriod, 2*time.Second)
+	waitForCount(t, DD*NN, members, checkPeriod, 2*time.Second)
 	validateSameStore(t, members)
 	members[0].Write(newTestItem("David", "Benque"))
-	waitForCount(DD*NN+1, members, checkPeriod, 2*time.Second)
+	waitForCount(t, DD*NN+1, members, checkPeriod, 2*time.Second)
 	validateSameStore(t, members)
 	members[0].Write(newTestItem("David", "dbenque"))
 	waitForCheck(members, checkPeriod, KeyIDPair{Key: "David", ID: members[0].id},
@@ -171,10 +190,10 @@ func addOnlySequence(t *testing.T, members []*testMember, engines []*Engine) {
 func allSequence(t *testing.T, members []*testMember, engines []*Engine) {
 	stop := make(chan struct{})
 	runEngines(stop, engines)
-	waitForCount(DD*NN, members, checkPeriod, 2*time.Second)
+	waitForCount(t, DD*NN, members, checkPeriod, 2*time.Second)
 	validateSameStore(t, members)
 	members[0].Write(newTestItem("David", "Benque"))
-	waitForCount(DD*NN+1, members, checkPeriod, 2*time.Second)
+	waitForCount(t, DD*NN+1, members, checkPeriod, 2*time.Second)
 	validateSameStore(t, members)
 	members[0].Write(newTestItem("David", "dbenque"))
 	waitForCheck(members, checkPeriod, KeyIDPair{Key: "David", ID: members[0].id},
@@ -187,7 +206,7 @@ func allSequence(t *testing.T, members []*testMember, engines []*Engine) {
 		}, 2*time.Second)
 	validateSameStore(t, members)
 	members[0].Remove("David")
-	waitForCount(DD*NN, members, checkPeriod, 2*time.Second)
+	waitForCount(t, DD*NN, members, checkPeriod, 2*time.Second)
 	validateSameStore(t, members)
 	close(stop)
 }
@@ -202,6 +221,7 @@ func TestEngine(t *testing.T) {
 		syncPeriod    time.Duration
 		scenario      func(*testing.T, []*testMember, []*Engine)
 		dot           bool
+		options       []Option
 	}{
 		{
 			name:          "line_All",
@@ -329,10 +349,70 @@ func TestEngine(t *testing.T) {
 			syncPeriod:    syncPeriod,
 			scenario:      addOnlySequence,
 		},
+		{
+			name:          "line_All_Merkle",
+			topo:          "line",
+			nbMember:      NN,
+			nbData:        DD,
+			panicOnDelete: false,
+			syncPeriod:    syncPeriod,
+			scenario:      allSequence,
+			options:       []Option{WithIndexMode(IndexModeMerkle)},
+		},
+		{
+			name:          "circle2_AddOnly_Merkle",
+			topo:          "circle2",
+			nbMember:      NN,
+			nbData:        DD,
+			panicOnDelete: true,
+			syncPeriod:    syncPeriod,
+			scenario:      addOnlySequence,
+			options:       []Option{WithIndexMode(IndexModeMerkle)},
+		},
+		{
+			name:          "random4_All_Merkle",
+			topo:          "random4",
+			nbMember:      NN,
+			nbData:        DD,
+			panicOnDelete: false,
+			syncPeriod:    syncPeriod,
+			scenario:      allSequence,
+			options:       []Option{WithIndexMode(IndexModeMerkle)},
+		},
+		{
+			name:          "line_All_Delta",
+			topo:          "line",
+			nbMember:      NN,
+			nbData:        DD,
+			panicOnDelete: false,
+			syncPeriod:    syncPeriod,
+			scenario:      allSequence,
+			options:       []Option{WithIndexMode(IndexModeDelta)},
+		},
+		{
+			name:          "circle2_AddOnly_Delta",
+			topo:          "circle2",
+			nbMember:      NN,
+			nbData:        DD,
+			panicOnDelete: true,
+			syncPeriod:    syncPeriod,
+			scenario:      addOnlySequence,
+			options:       []Option{WithIndexMode(IndexModeDelta)},
+		},
+		{
+			name:          "random4_All_Delta",
+			topo:          "random4",
+			nbMember:      NN,
+			nbData:        DD,
+			panicOnDelete: false,
+			syncPeriod:    syncPeriod,
+			scenario:      allSequence,
+			options:       []Option{WithIndexMode(IndexModeDelta)},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			members, engines := prepareTest(tt.nbMember, tt.nbData, tt.topo, tt.panicOnDelete, tt.syncPeriod)
+			members, engines := prepareTest(tt.nbMember, tt.nbData, tt.topo, tt.panicOnDelete, tt.syncPeriod, tt.options...)
 			run := func(name string) {
 				defer func() {
 					if r := recover(); r != nil {
@@ -376,6 +456,35 @@ func buildConnectionMap(members []*testMember) map[utils.VertexWithID][]utils.Ve
 	return result
 }
 
+//racingMember records a newer version of M0 while its indexes are read, like a DataResponse received meanwhile
+type racingMember struct {
+	*testMember
+	engine    *Engine
+	buildTime time.Time
+}
+
+func (m *racingMember) GetIndexes() IndexMap {
+	im := m.testMember.GetIndexes()
+	m.engine.updateIndexTime("M0", m.buildTime)
+	return im
+}
+
+func TestBuildIndexMapVersion(t *testing.T) {
+	now := time.Now()
+	m := &racingMember{testMember: newTestMember("M1", NewMapStore()), buildTime: now.Add(time.Second)}
+	m.engine = NewEngine(m, time.Hour)
+	item := newTestItem("David", "Benque")
+	item.Owner = "M0"
+	item.Time = now
+	m.engine.put(Items{item})
+	m.engine.updateIndexTime("M0", now)
+
+	// the keys read are the ones of the version known before the read
+	if bt := m.engine.buildIndexMap().Indexes["M0"].BuildTime; !bt.Equal(now) {
+		t.Fatalf("the keys of %s were advertised with the BuildTime %s recorded meanwhile", now, bt)
+	}
+}
+
 func TestFuzzyAddOnly(t *testing.T) {
 	N := r1.Intn(30) + 10
 	members := make([]*testMember, N)
@@ -411,7 +520,7 @@ func TestFuzzyAddOnly(t *testing.T) {
 			val := fmt.Sprintf("%d", r1.Intn(1000))
 			members[i].Write(newTestItem(name, val))
 		}
-		go engines[i].Run(stop)
+		go engines[i].Run(stopContext(stop))
 	}
 
 	var wg sync.WaitGroup
@@ -432,6 +541,7 @@ func (v *vertex) ID() string {
 	return string(v.testMember.ID())
 }
 func validateSameStore(t *testing.T, members []*testMember) (ok bool) {
+	t.Helper()
 	for i := range members {
 		for j := range members {
 			storeI := members[i].GetStore().(*MapStore)
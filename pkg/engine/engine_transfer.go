@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+//TransferableItem is implemented by the items whose ownership can be handed over
+type TransferableItem interface {
+	Item
+	//Transfer returns a copy of the item owned by id and stamped at t
+	Transfer(id ID, t time.Time) Item
+}
+
+//ErrNotTransferable is returned by Transfer when an item does not implement TransferableItem
+var ErrNotTransferable = errors.New("item not transferable")
+
+//HandoverTTL is how long a handover is relayed to the mesh
+var HandoverTTL = 24 * time.Hour
+
+//handoverID identifies a handover
+type handoverID struct {
+	from ID
+	to   ID
+	time time.Time
+}
+
+type handoverState struct {
+	handover Handover
+	since    time.Time
+}
+
+//pendingKey is a key handed over to the local member that it does not hold yet
+type pendingKey struct {
+	key    StampedKey
+	source ID
+}
+
+//Transfer hands over the keys of the local member to the member to, all the keys of the local member if keys is nil.
+//The handover is announced with the next IndexMap; from then on the local member must not write these keys
+//and to takes over their writes
+func (e *Engine) Transfer(to ID, keys Keys) error {
+	if to == e.local.ID() {
+		return fmt.Errorf("can't transfer keys of %s to itself", to)
+	}
+	wanted := map[Key]struct{}{}
+	for _, k := range keys {
+		wanted[k] = struct{}{}
+	}
+	selected := StampedKeys{}
+	kps := KeyIDPairs{}
+	for _, k := range e.local.GetIndexes().Indexes[e.local.ID()].StampedKeys {
+		if _, ok := wanted[k.Key]; keys == nil || ok {
+			selected = append(selected, k)
+			kps = append(kps, KeyIDPair{ID: e.local.ID(), Key: k.Key})
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no key of %s to transfer to %s", e.local.ID(), to)
+	}
+	for _, i := range e.local.GetData(kps) {
+		if _, ok := i.(TransferableItem); !ok {
+			return fmt.Errorf("%w: %s", ErrNotTransferable, i.GetKey())
+		}
+	}
+	e.applyHandover(Handover{From: e.local.ID(), To: to, Time: e.clock.Now(), Keys: selected}, e.local.ID())
+	return nil
+}
+
+//applyHandover moves the handed over items from the shard of From to the shard of To. A key is only moved if its
+//version was written before the handover; a stale version keeps its stamp so that the version of To replaces it.
+//When the local member is To, the keys it does not hold are fetched from source and listed in its index meanwhile
+func (e *Engine) applyHandover(h Handover, source ID) {
+	e.handoversMutex.Lock()
+	id := handoverID{from: h.From, to: h.To, time: h.Time}
+	if _, ok := e.handovers[id]; ok {
+		e.handoversMutex.Unlock()
+		return
+	}
+	e.handovers[id] = handoverState{handover: h, since: time.Now()}
+	e.handoversMutex.Unlock()
+	e.clock.Observe(h.Time)
+
+	versions := map[Key]StampedKey{}
+	kps := KeyIDPairs{}
+	for _, k := range h.Keys {
+		versions[k.Key] = k
+		kps = append(kps, KeyIDPair{ID: h.From, Key: k.Key})
+	}
+	moved := Items{}
+	movedKeys := KeyIDPairs{}
+	for _, i := range e.local.GetData(kps) {
+		sk := i.StampedKey()
+		t, ok := i.(TransferableItem)
+		if !ok || sk.Timestamp.After(h.Time) {
+			continue // written by From after the handover
+		}
+		stamp := h.Time
+		if sk.Timestamp.Before(versions[sk.Key].Timestamp) {
+			stamp = sk.Timestamp
+		}
+		moved = append(moved, t.Transfer(h.To, stamp))
+		movedKeys = append(movedKeys, KeyIDPair{ID: h.From, Key: sk.Key})
+		delete(versions, sk.Key)
+	}
+	// the new shard is written before the old one is deleted: the items are never missing
+	e.put(moved)
+	if len(movedKeys) > 0 {
+		e.delete(movedKeys)
+	}
+
+	if h.To != e.local.ID() || len(versions) == 0 {
+		return
+	}
+	e.handoversMutex.Lock()
+	for _, k := range versions {
+		k.Timestamp = h.Time
+		e.pendingKeys[k.Key] = pendingKey{key: k, source: source}
+	}
+	e.handoversMutex.Unlock()
+	e.requestPendingKeys(nil)
+}
+
+//requestPendingKeys fetches the keys handed over to the local member that it does not hold yet and adds them to
+//its index so that the replicas that already moved them keep them
+func (e *Engine) requestPendingKeys(index *Index) {
+	for _, rq := range e.pendingRequests(index) {
+		e.requestKeys(rq)
+	}
+}
+
+//pendingRequests adds the keys handed over to the local member to its index and returns the DataRequests of the
+//ones it does not hold yet
+func (e *Engine) pendingRequests(index *Index) []DataRequest {
+	e.handoversMutex.Lock()
+	defer e.handoversMutex.Unlock()
+	if len(e.pendingKeys) == 0 {
+		return nil
+	}
+	if index != nil {
+		held := map[Key]struct{}{}
+		for _, k := range index.StampedKeys {
+			held[k.Key] = struct{}{}
+		}
+		for key, p := range e.pendingKeys {
+			if _, ok := held[key]; ok {
+				delete(e.pendingKeys, key)
+				continue
+			}
+			index.StampedKeys = append(index.StampedKeys, p.key)
+		}
+	}
+	requests := map[ID]KeyIDPairs{}
+	for key, p := range e.pendingKeys {
+		requests[p.source] = append(requests[p.source], KeyIDPair{ID: e.local.ID(), Key: key})
+	}
+	rqs := []DataRequest{}
+	for source, kps := range requests {
+		if source == e.local.ID() || e.isDead(source) {
+			continue
+		}
+		rqs = append(rqs, DataRequest{KeyIDPairs: kps, RequestDestination: source, RequestSource: e.local.ID()})
+	}
+	return rqs
+}
+
+//addHandovers relays the handovers younger than HandoverTTL in the IndexMap, until they converge
+func (e *Engine) addHandovers(indexMap IndexMap, now time.Time) IndexMap {
+	e.handoversMutex.Lock()
+	handovers := []Handover{}
+	for id, h := range e.handovers {
+		if now.Sub(h.since) > HandoverTTL {
+			delete(e.handovers, id)
+			continue
+		}
+		handovers = append(handovers, h.handover)
+	}
+	e.handoversMutex.Unlock()
+	for _, h := range handovers {
+		if !e.handoverConverged(h) {
+			indexMap.Handovers = append(indexMap.Handovers, h)
+		}
+	}
+	return indexMap
+}
+
+//handoverConverged returns true once the local member holds a version of the shard of To built after the handover
+//that lists its keys: To applied it, and its index brings the keys to the members that did not. The handover is
+//still remembered until HandoverTTL so that it is not applied twice. A key that the local member does not replicate,
+//or that To removed meanwhile, keeps the handover relayed until HandoverTTL
+func (e *Engine) handoverConverged(h Handover) bool {
+	if t, _ := e.getIndexTime(h.To); t.Before(h.Time) {
+		return false
+	}
+	held := map[Key]struct{}{}
+	for _, k := range e.localIndexes()[h.To].StampedKeys {
+		held[k.Key] = struct{}{}
+	}
+	for _, k := range h.Keys {
+		if _, ok := held[k.Key]; !ok {
+			return false
+		}
+	}
+	return true
+}
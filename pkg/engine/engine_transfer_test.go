@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+//watchGap reports a key missing from both shards of a member while it moves from the shard from to the shard to
+func watchGap(members []*testMember, keys Keys, from, to ID, stop chan struct{}) func() bool {
+	var wg sync.WaitGroup
+	gap := false
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			for _, m := range members {
+				for _, k := range keys {
+					// the old shard is read first: the new one is written before the old one is deleted
+					if m.GetStore().Get(KeyIDPair{ID: from, Key: k}) != nil || m.GetStore().Get(KeyIDPair{ID: to, Key: k}) != nil {
+						continue
+					}
+					gap = true
+					return
+				}
+			}
+		}
+	}()
+	return func() bool {
+		wg.Wait()
+		return gap
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	keys := Keys{"David", "Gemma"}
+	for _, k := range keys {
+		members[0].Write(newTestItem(k, "v"))
+	}
+	waitForCount(t, 2, members, checkPeriod, 2*time.Second)
+
+	stopWatch := make(chan struct{})
+	gap := watchGap(members, keys, members[0].ID(), members[2].ID(), stopWatch)
+	if err := engines[0].Transfer(members[2].ID(), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		waitForCheck(members, checkPeriod, KeyIDPair{ID: members[2].ID(), Key: k}, func(i Item) bool { return i != nil }, 2*time.Second)
+	}
+	time.Sleep(10 * syncPeriod)
+	close(stopWatch)
+	if gap() {
+		t.Fatalf("a key was missing during the transfer")
+	}
+	for _, m := range members {
+		if c := m.GetStore().(*MapStore).Count(); c != 2 {
+			t.Fatalf("%s has %d items after the transfer, expected 2", m.ID(), c)
+		}
+	}
+
+	// the new owner takes over the writes
+	members[2].Write(newTestItem("David", "Benque"))
+	waitForCheck(members, checkPeriod, KeyIDPair{ID: members[2].ID(), Key: "David"},
+		func(i Item) bool { return i != nil && i.(*testItem).Value == "Benque" }, 2*time.Second)
+}
+
+func TestTransferFetchedByNewOwner(t *testing.T) {
+	// M2 does not replicate the items of M0, it fetches them when they are handed over
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	engines[2].SetInterest(Interest{Owners: []ID{"M2"}})
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	members[0].Write(newTestItem("David", "v"))
+	waitForCount(t, 1, members[:2], checkPeriod, 2*time.Second)
+
+	stopWatch := make(chan struct{})
+	gap := watchGap(members[:2], Keys{"David"}, members[0].ID(), members[2].ID(), stopWatch)
+	if err := engines[0].Transfer(members[2].ID(), Keys{"David"}); err != nil {
+		t.Fatal(err)
+	}
+	waitForCheck(members, checkPeriod, KeyIDPair{ID: members[2].ID(), Key: "David"}, func(i Item) bool { return i != nil }, 2*time.Second)
+	time.Sleep(10 * syncPeriod)
+	close(stopWatch)
+	if gap() {
+		t.Fatalf("a key was missing during the transfer")
+	}
+}
+
+func TestTransferNothing(t *testing.T) {
+	members, engines := prepareTest(2, 0, "line", false, syncPeriod)
+	if err := engines[0].Transfer(members[1].ID(), Keys{"unknown"}); err == nil {
+		t.Fatalf("expected an error when no key is transferred")
+	}
+}
+
+func TestTransferRelayedUntilConverged(t *testing.T) {
+	members, engines := prepareTest(3, 0, "line", false, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	keys := Keys{"David", "Gemma"}
+	for _, k := range keys {
+		members[0].Write(newTestItem(k, "v"))
+	}
+	waitForCount(t, 2, members, checkPeriod, 2*time.Second)
+
+	if err := engines[0].Transfer(members[2].ID(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if h := engines[0].addHandovers(IndexMap{}, time.Now()).Handovers; len(h) != 1 {
+		t.Fatalf("the handover is not relayed: %v", h)
+	}
+	for _, k := range keys {
+		waitForCheck(members, checkPeriod, KeyIDPair{ID: members[2].ID(), Key: k}, func(i Item) bool { return i != nil }, 2*time.Second)
+	}
+	// the IndexMaps don't carry the keys of the handover once every member holds the version of M2 that lists them
+	deadline := time.Now().Add(2 * time.Second)
+	for _, e := range engines {
+		for len(e.addHandovers(IndexMap{}, time.Now()).Handovers) > 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s still relays the handover after it converged", e.local.ID())
+			}
+			time.Sleep(checkPeriod)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+//EventType is the kind of change applied by the engine to the local member
+type EventType int
+
+const (
+	//EventAdded an item of another owner was received for the first time
+	EventAdded EventType = iota
+	//EventUpdated a newer version of an item was received
+	EventUpdated
+	//EventDeleted an item was removed by its owner or purged with a retired owner
+	EventDeleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventUpdated:
+		return "updated"
+	case EventDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+//Event is a replicated change. Old is nil for EventAdded and New is nil for EventDeleted
+type Event struct {
+	Type EventType
+	KeyIDPair
+	Old Item
+	New Item
+}
+
+//WatchFilter selects the events delivered to a Watcher. The zero value selects all the events
+type WatchFilter struct {
+	//Owners of the items, all the owners if empty
+	Owners []ID
+	//KeyPrefix of the items
+	KeyPrefix Key
+}
+
+func (f WatchFilter) match(kp KeyIDPair) bool {
+	if !strings.HasPrefix(string(kp.Key), string(f.KeyPrefix)) {
+		return false
+	}
+	if len(f.Owners) == 0 {
+		return true
+	}
+	for _, id := range f.Owners {
+		if id == kp.ID {
+			return true
+		}
+	}
+	return false
+}
+
+//DefaultWatchBuffer is the number of events buffered for a Watcher created with a buffer of 0
+const DefaultWatchBuffer = 256
+
+//Watcher receives the changes applied by the engine to the local member. The engine never waits for a watcher:
+//the events that don't fit in its buffer are dropped and counted
+type Watcher struct {
+	engine  *Engine
+	filter  WatchFilter
+	events  chan Event
+	dropped uint64
+}
+
+//Watch subscribes to the changes received from the mesh that match filter. The writes of the local member
+//are not notified. The watcher must be stopped when it is no longer read
+func (e *Engine) Watch(filter WatchFilter, buffer int) *Watcher {
+	if buffer <= 0 {
+		buffer = DefaultWatchBuffer
+	}
+	w := &Watcher{
+		engine: e,
+		filter: filter,
+		events: make(chan Event, buffer),
+	}
+	e.watchersMutex.Lock()
+	defer e.watchersMutex.Unlock()
+	e.watchers[w] = struct{}{}
+	return w
+}
+
+//Events returns the channel of the events, it is closed by Stop
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+//Dropped returns the number of events lost because the buffer was full. The reader can then resync from the Store
+func (w *Watcher) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+//Stop unsubscribes the watcher and closes its channel
+func (w *Watcher) Stop() {
+	w.engine.watchersMutex.Lock()
+	defer w.engine.watchersMutex.Unlock()
+	if _, ok := w.engine.watchers[w]; ok {
+		delete(w.engine.watchers, w)
+		close(w.events)
+	}
+}
+
+//watched returns true if at least one watcher is subscribed
+func (e *Engine) watched() bool {
+	e.watchersMutex.RLock()
+	defer e.watchersMutex.RUnlock()
+	return len(e.watchers) > 0
+}
+
+//notify delivers the events to the watchers
+func (e *Engine) notify(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	e.watchersMutex.RLock()
+	defer e.watchersMutex.RUnlock()
+	for w := range e.watchers {
+		for _, ev := range events {
+			if !w.filter.match(ev.KeyIDPair) {
+				continue
+			}
+			select {
+			case w.events <- ev:
+			default:
+				atomic.AddUint64(&w.dropped, 1)
+			}
+		}
+	}
+}
+
+//currentItems returns the items stored by the local member for kps
+func (e *Engine) currentItems(kps KeyIDPairs) map[KeyIDPair]Item {
+	current := map[KeyIDPair]Item{}
+	for _, i := range e.local.GetData(kps) {
+		current[KeyIDPair{ID: i.OwnedBy(), Key: i.GetKey()}] = i
+	}
+	return current
+}
+
+//put stores the items in the local member and notifies the watchers
+func (e *Engine) put(items Items) {
+	if len(items) == 0 {
+		return
+	}
+	if !e.watched() {
+		e.local.Put(items)
+		e.invalidateLocalIndexes()
+		return
+	}
+	kps := make(KeyIDPairs, 0, len(items))
+	for _, i := range items {
+		kps = append(kps, KeyIDPair{ID: i.OwnedBy(), Key: i.GetKey()})
+	}
+	current := e.currentItems(kps)
+	e.local.Put(items)
+	e.invalidateLocalIndexes()
+	events := make([]Event, 0, len(items))
+	for idx, i := range items {
+		ev := Event{Type: EventAdded, KeyIDPair: kps[idx], New: i}
+		if old, ok := current[kps[idx]]; ok {
+			ev.Type = EventUpdated
+			ev.Old = old
+		}
+		events = append(events, ev)
+	}
+	e.notify(events)
+}
+
+//delete removes the keys from the local member and notifies the watchers
+func (e *Engine) delete(kps KeyIDPairs) {
+	if !e.watched() {
+		e.local.Delete(kps)
+		e.invalidateLocalIndexes()
+		return
+	}
+	current := e.currentItems(kps)
+	e.local.Delete(kps)
+	e.invalidateLocalIndexes()
+	e.notifyDeleted(current)
+}
+
+//purgeOwner removes the shard of owner id from the local member and notifies the watchers
+func (e *Engine) purgeOwner(id ID) {
+	if !e.watched() {
+		e.local.PurgeOwner(id)
+		e.invalidateLocalIndexes()
+		return
+	}
+	kps := KeyIDPairs{}
+	for _, k := range e.local.GetIndexes().Indexes[id].StampedKeys {
+		kps = append(kps, KeyIDPair{ID: id, Key: k.Key})
+	}
+	current := e.currentItems(kps)
+	e.local.PurgeOwner(id)
+	e.invalidateLocalIndexes()
+	e.notifyDeleted(current)
+}
+
+func (e *Engine) notifyDeleted(deleted map[KeyIDPair]Item) {
+	events := make([]Event, 0, len(deleted))
+	for kp, old := range deleted {
+		events = append(events, Event{Type: EventDeleted, KeyIDPair: kp, Old: old})
+	}
+	e.notify(events)
+}
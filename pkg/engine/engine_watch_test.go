@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, w *Watcher) Event {
+	select {
+	case ev := <-w.Events():
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no event received")
+	}
+	return Event{}
+}
+
+func TestWatch(t *testing.T) {
+	members, engines := prepareTest(2, 0, "line", false, syncPeriod)
+	w := engines[1].Watch(WatchFilter{Owners: []ID{members[0].ID()}, KeyPrefix: "user/"}, 0)
+	defer w.Stop()
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+
+	// filtered out: key prefix and owner
+	members[0].Write(newTestItem("group/admin", "x"))
+	members[1].Write(newTestItem("user/Benque", "x"))
+	waitForCount(t, 2, members, checkPeriod, 2*time.Second)
+
+	kp := KeyIDPair{ID: members[0].ID(), Key: "user/David"}
+	members[0].Write(newTestItem(kp.Key, "Benque"))
+	ev := nextEvent(t, w)
+	if ev.Type != EventAdded || ev.KeyIDPair != kp || ev.Old != nil || ev.New.(*testItem).Value != "Benque" {
+		t.Fatalf("unexpected event %s %v", ev.Type, ev)
+	}
+
+	members[0].Write(newTestItem(kp.Key, "dbenque"))
+	ev = nextEvent(t, w)
+	if ev.Type != EventUpdated || ev.Old.(*testItem).Value != "Benque" || ev.New.(*testItem).Value != "dbenque" {
+		t.Fatalf("unexpected event %s %v", ev.Type, ev)
+	}
+
+	members[0].Remove(kp.Key)
+	ev = nextEvent(t, w)
+	if ev.Type != EventDeleted || ev.KeyIDPair != kp || ev.Old.(*testItem).Value != "dbenque" || ev.New != nil {
+		t.Fatalf("unexpected event %s %v", ev.Type, ev)
+	}
+
+	w.Stop()
+	if _, ok := <-w.Events(); ok {
+		t.Fatalf("the events channel is not closed")
+	}
+}
+
+func TestWatchDropped(t *testing.T) {
+	members, engines := prepareTest(2, 5, "line", true, syncPeriod)
+	w := engines[1].Watch(WatchFilter{}, 2)
+	defer w.Stop()
+	stop := make(chan struct{})
+	defer close(stop)
+	runEngines(stop, engines)
+	waitForCount(t, 10, members, checkPeriod, 2*time.Second)
+
+	if len(w.Events()) != 2 {
+		t.Fatalf("expected a full buffer, got %d events", len(w.Events()))
+	}
+	if d := w.Dropped(); d != 3 {
+		t.Fatalf("expected 3 dropped events, got %d", d)
+	}
+}
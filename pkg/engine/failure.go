@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+//PeerState is the health of a neighbor as seen by the failure detector
+type PeerState int
+
+const (
+	//PeerAlive the neighbor sends its messages at the expected pace
+	PeerAlive PeerState = iota
+	//PeerSuspect the neighbor is late, it may be down
+	PeerSuspect
+	//PeerDead the neighbor is considered down, no DataRequest is sent to it until it is heard again
+	PeerDead
+)
+
+func (s PeerState) String() string {
+	switch s {
+	case PeerAlive:
+		return "alive"
+	case PeerSuspect:
+		return "suspect"
+	case PeerDead:
+		return "dead"
+	}
+	return "unknown"
+}
+
+const (
+	//DefaultSuspectPhi phi above which a neighbor is suspect
+	DefaultSuspectPhi = 3.0
+	//DefaultDeadPhi phi above which a neighbor is dead
+	DefaultDeadPhi = 8.0
+	//heartbeatWindow number of inter-arrival times used to estimate the pace of a neighbor
+	heartbeatWindow = 32
+)
+
+//heartbeatHistory records when a neighbor was heard
+type heartbeatHistory struct {
+	last      time.Time
+	intervals []time.Duration
+	next      int
+	sum       time.Duration
+}
+
+func (h *heartbeatHistory) add(interval time.Duration) {
+	if len(h.intervals) < heartbeatWindow {
+		h.intervals = append(h.intervals, interval)
+	} else {
+		h.sum -= h.intervals[h.next]
+		h.intervals[h.next] = interval
+		h.next = (h.next + 1) % heartbeatWindow
+	}
+	h.sum += interval
+}
+
+//phi is the suspicion level of the accrual failure detector: -log10 of the probability to wait more than the
+//elapsed time for the next heartbeat, the inter-arrival times following an exponential distribution whose mean
+//is at least floor
+func (h *heartbeatHistory) phi(now time.Time, floor time.Duration) float64 {
+	mean := float64(h.sum) / float64(len(h.intervals))
+	if mean < float64(floor) {
+		mean = float64(floor) // the neighbor may slow down to floor when the mesh is quiet
+	}
+	if mean <= 0 {
+		return 0
+	}
+	return float64(now.Sub(h.last)) / mean * math.Log10(math.E)
+}
+
+//failureDetector is a phi accrual failure detector. Any message received from a neighbor is a heartbeat, the
+//IndexMap sent at each sync period, or every few periods with a gossip strategy, guarantees a regular pace
+type failureDetector struct {
+	sync.Mutex
+	expected   time.Duration
+	floor      time.Duration
+	periods    int
+	suspectPhi float64
+	deadPhi    float64
+	peers      map[ID]*heartbeatHistory
+}
+
+func newFailureDetector(expected time.Duration) *failureDetector {
+	return &failureDetector{
+		expected:   expected,
+		suspectPhi: DefaultSuspectPhi,
+		deadPhi:    DefaultDeadPhi,
+		peers:      map[ID]*heartbeatHistory{},
+	}
+}
+
+//heard records a heartbeat of the neighbor id
+func (d *failureDetector) heard(id ID, now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+	h, ok := d.peers[id]
+	if !ok {
+		// until it is measured, the pace of the neighbor is assumed to be the local sync period
+		h = &heartbeatHistory{}
+		h.add(d.expected)
+		d.peers[id] = h
+	} else {
+		h.add(now.Sub(h.last))
+	}
+	h.last = now
+}
+
+//watch starts monitoring a neighbor that was not heard yet
+func (d *failureDetector) watch(id ID, now time.Time) {
+	d.Lock()
+	_, ok := d.peers[id]
+	d.Unlock()
+	if !ok {
+		d.heard(id, now)
+	}
+}
+
+func (d *failureDetector) forget(id ID) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.peers, id)
+}
+
+//setPeriods records the number of sync periods between two IndexMaps sent to the same peer by the gossip strategy
+func (d *failureDetector) setPeriods(periods int) {
+	d.Lock()
+	defer d.Unlock()
+	d.periods = periods
+}
+
+//floorOf returns the minimum pace expected from the neighbors: the sync period, or max of the adaptive sync, times
+//the periods of the gossip. A neighbor sends an IndexMap at that pace whatever the other messages it sent before:
+//a burst of pushes or index requests must not make it look late
+func (d *failureDetector) floorOf() time.Duration {
+	pace := d.expected
+	if d.floor > pace {
+		pace = d.floor
+	}
+	if d.periods > 1 {
+		pace *= time.Duration(d.periods)
+	}
+	return pace
+}
+
+func (d *failureDetector) stateOf(h *heartbeatHistory, now time.Time) PeerState {
+	phi := h.phi(now, d.floorOf())
+	switch {
+	case phi >= d.deadPhi:
+		return PeerDead
+	case phi >= d.suspectPhi:
+		return PeerSuspect
+	}
+	return PeerAlive
+}
+
+//state returns the state of the neighbor id, false if it is not monitored
+func (d *failureDetector) state(id ID, now time.Time) (PeerState, bool) {
+	d.Lock()
+	defer d.Unlock()
+	h, ok := d.peers[id]
+	if !ok {
+		return PeerAlive, false
+	}
+	return d.stateOf(h, now), true
+}
+
+func (d *failureDetector) states(now time.Time) map[ID]PeerState {
+	d.Lock()
+	defer d.Unlock()
+	states := make(map[ID]PeerState, len(d.peers))
+	for id, h := range d.peers {
+		states[id] = d.stateOf(h, now)
+	}
+	return states
+}
+
+//WithFailureDetector sets the phi thresholds above which a neighbor is suspect and dead
+func WithFailureDetector(suspectPhi, deadPhi float64) Option {
+	return func(e *Engine) {
+		e.failureDetector.suspectPhi = suspectPhi
+		e.failureDetector.deadPhi = deadPhi
+	}
+}
+
+//PeerState returns the state of the neighbor id, false if the engine never exchanged with it
+func (e *Engine) PeerState(id ID) (PeerState, bool) {
+	return e.failureDetector.state(id, time.Now())
+}
+
+//Peers returns the state of all the neighbors monitored by the engine
+func (e *Engine) Peers() map[ID]PeerState {
+	return e.failureDetector.states(time.Now())
+}
+
+//isDead returns true if the neighbor id is monitored and dead
+func (e *Engine) isDead(id ID) bool {
+	s, ok := e.failureDetector.state(id, time.Now())
+	return ok && s == PeerDead
+}
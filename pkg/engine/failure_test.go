@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailureDetector(t *testing.T) {
+	d := newFailureDetector(10 * time.Millisecond)
+	t0 := time.Now()
+	if _, ok := d.state("M1", t0); ok {
+		t.Fatalf("a peer never heard should not be monitored")
+	}
+	d.heard("M1", t0)
+	for _, c := range []struct {
+		elapsed time.Duration
+		state   PeerState
+	}{
+		{10 * time.Millisecond, PeerAlive},
+		{100 * time.Millisecond, PeerSuspect},
+		{300 * time.Millisecond, PeerDead},
+	} {
+		if s, _ := d.state("M1", t0.Add(c.elapsed)); s != c.state {
+			t.Fatalf("after %v: expected %v, got %v", c.elapsed, c.state, s)
+		}
+	}
+	d.heard("M1", t0.Add(300*time.Millisecond))
+	if s, _ := d.state("M1", t0.Add(300*time.Millisecond)); s != PeerAlive {
+		t.Fatalf("a peer heard again should be alive, got %v", s)
+	}
+	// the pace of the peer is learnt: a slow peer is not suspected at its own pace
+	for i := 2; i < 3*heartbeatWindow; i++ {
+		d.heard("M1", t0.Add(time.Duration(i)*300*time.Millisecond))
+	}
+	last := t0.Add(time.Duration(3*heartbeatWindow-1) * 300 * time.Millisecond)
+	if s, _ := d.state("M1", last.Add(300*time.Millisecond)); s != PeerAlive {
+		t.Fatalf("a slow peer should be alive at its pace, got %v", s)
+	}
+	d.forget("M1")
+	if _, ok := d.state("M1", last); ok {
+		t.Fatalf("a forgotten peer should not be monitored")
+	}
+}
+
+func TestFailureDetectorBurst(t *testing.T) {
+	d := newFailureDetector(time.Second)
+	t0 := time.Now()
+	// a burst of pushes shrinks the measured pace
+	for i := 0; i < 40; i++ {
+		d.heard("M1", t0.Add(time.Duration(i)*time.Millisecond))
+	}
+	last := t0.Add(39 * time.Millisecond)
+	for _, elapsed := range []time.Duration{100 * time.Millisecond, 500 * time.Millisecond, 900 * time.Millisecond} {
+		if s, _ := d.state("M1", last.Add(elapsed)); s != PeerAlive {
+			t.Fatalf("silent for %v after a burst, shorter than the sync period: expected alive, got %v", elapsed, s)
+		}
+	}
+}
+
+func TestDeadPeerNotRequested(t *testing.T) {
+	members, engines := prepareTest(2, 1, "line", true, syncPeriod)
+	stop := make(chan struct{})
+	defer close(stop)
+	// M0 does not run: M1 never hears it
+	runEngines(stop, engines[1:])
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if s, ok := engines[1].PeerState(members[0].ID()); ok && s == PeerDead {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("M0 not detected as dead: %v", engines[1].Peers())
+		}
+		time.Sleep(checkPeriod)
+	}
+	engines[1].CheckAndGetUpdates(members[0].GetIndexes())
+	time.Sleep(10 * checkPeriod)
+	if n := len(members[0].connector.(*ConnectorImpl).RequestKeysCh); n != 0 {
+		t.Fatalf("%d requests sent to a dead peer", n)
+	}
+
+	// M0 comes back
+	runEngines(stop, engines[:1])
+	waitForCount(t, 2, members, checkPeriod, 2*time.Second)
+	if s, _ := engines[1].PeerState(members[0].ID()); s != PeerAlive {
+		t.Fatalf("M0 should be alive, got %v", s)
+	}
+}
@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -9,10 +11,11 @@ import (
 //============================ Item implementation for test =========================
 
 type testItem struct {
-	Key   Key
-	Time  time.Time
-	Value string
-	Owner ID
+	Key     Key
+	Time    time.Time
+	Value   string
+	Owner   ID
+	Expires time.Time
 }
 
 var _ Item = &testItem{}
@@ -32,6 +35,7 @@ func (i *testItem) StampedKey() StampedKey {
 	return StampedKey{
 		Key:       i.Key,
 		Timestamp: i.Time,
+		Expires:   i.Expires,
 	}
 }
 func (i *testItem) OwnedBy() ID {
@@ -41,6 +45,12 @@ func (i *testItem) DeepCopy() Item {
 	j := *i
 	return &j
 }
+func (i *testItem) Transfer(id ID, t time.Time) Item {
+	j := *i
+	j.Owner = id
+	j.Time = t
+	return &j
+}
 
 //============================ Member  implementation for test =========================
 
@@ -48,13 +58,14 @@ type testMember struct {
 	id        ID
 	store     Store
 	connector Connector
+	clock     Clock
 }
 
 var _ LocalMember = &testMember{}
 
-func newTestMember(id string, store Store) *testMember {
-	m := &testMember{id: ID(id), store: store}
-	m.connector = NewConnector(m, newTestConnector)
+func newTestMember(id string, store Store, options ...ConnectorOption) *testMember {
+	m := &testMember{id: ID(id), store: store, clock: NewHybridClock(0)}
+	m.connector = NewConnector(m, newTestConnector, options...)
 	return m
 }
 
@@ -66,6 +77,9 @@ func (m *testMember) GetStore() Store {
 	return m.store
 }
 
+func (m *testMember) Clock() Clock {
+	return m.clock
+}
 func (m *testMember) GetConnector() Connector {
 	return m.connector
 }
@@ -97,9 +111,12 @@ func (m *testMember) Delete(kps KeyIDPairs) {
 func (m *testMember) Put(items Items) {
 	m.store.MultiSet(items)
 }
+func (m *testMember) PurgeOwner(id ID) {
+	m.store.DeleteShard(id)
+}
 
 func (m *testMember) Write(item *testItem) {
-	item.Time = time.Now()
+	item.Time = m.clock.Now()
 
 	if item.Owner == "" {
 		item.Owner = m.id
@@ -110,6 +127,7 @@ func (m *testMember) Write(item *testItem) {
 	}
 
 	m.store.Set(item)
+	m.connector.Written(Items{item})
 }
 
 func (m *testMember) Remove(key Key) {
@@ -139,52 +157,175 @@ func (c *testConnector) GetLocalMember() LocalMember {
 	return c.localMember
 }
 
-func (c *testConnector) Connect(m Member) {
-	c.connect(m, true)
+func (c *testConnector) Connect(m Member) error {
+	return c.connect(m, true)
 }
 
-func (c *testConnector) connect(m Member, twoWays bool) {
+func (c *testConnector) connect(m Member, twoWays bool) error {
 	c.remoteHandling.Lock()
 	defer c.remoteHandling.Unlock()
 	mm, ok := m.(*testMember)
 	if !ok {
-		log.Fatalf("Can't connect member of different types.")
+		return fmt.Errorf("can't connect member %s of different type", m.ID())
 	}
 
 	c.remoteMember[mm.ID()] = mm
 
 	if twoWays {
-		mm.connector.(*ConnectorImpl).ConnectorCore.(*testConnector).connect(c.localMember, false)
+		return mm.connector.(*ConnectorImpl).ConnectorCore.(*testConnector).connect(c.localMember, false)
+	}
+	return nil
+}
+
+func (c *testConnector) Disconnect(id ID) error {
+	c.remoteHandling.Lock()
+	m := c.remoteMember[id]
+	delete(c.remoteMember, id)
+	c.remoteHandling.Unlock()
+	if m != nil {
+		// not under the lock: the remote may be disconnecting from us at the same time
+		m.connector.(*ConnectorImpl).ConnectorCore.(*testConnector).disconnect(c.localMember.ID())
 	}
+	return nil
+}
+
+func (c *testConnector) disconnect(id ID) {
+	c.remoteHandling.Lock()
+	defer c.remoteHandling.Unlock()
+	delete(c.remoteMember, id)
 }
 
-func (c *testConnector) ProcessIndexMap(index IndexMap) {
+func (c *testConnector) getRemote(id ID) (*testMember, error) {
 	c.remoteHandling.RLock()
 	defer c.remoteHandling.RUnlock()
-	for _, m := range c.remoteMember {
-		m.connector.(*ConnectorImpl).ReceiveIndexCh <- index
+	m := c.remoteMember[id]
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMember, id)
 	}
+	return m, nil
 }
 
-func (c *testConnector) ProcessDataRequest(rq DataRequest) {
-	items := c.localMember.GetData(rq.KeyIDPairs)
+func (c *testConnector) Peers() []ID {
 	c.remoteHandling.RLock()
 	defer c.remoteHandling.RUnlock()
-	m := c.remoteMember[rq.RequestSource]
-	if m != nil {
-		m.connector.(*ConnectorImpl).ReceiveDataCh <- DataResponse{Items: items, AssociatedBuildTime: rq.AssociatedBuildTime}
-	} else {
-		log.Fatalf("Lost member ProcessDataRequest")
+	peers := make([]ID, 0, len(c.remoteMember))
+	for id := range c.remoteMember {
+		peers = append(peers, id)
+	}
+	return peers
+}
+
+func (c *testConnector) ForwardIndexMap(id ID, index IndexMap) error {
+	m, err := c.getRemote(id)
+	if err != nil {
+		return err
+	}
+	impl := m.connector.(*ConnectorImpl)
+	select {
+	case impl.ReceiveIndexCh <- index:
+		return nil
+	case <-impl.Done():
+		return ErrStopped
 	}
 }
 
-func (c *testConnector) ForwardDataRequest(rq DataRequest) {
+func (c *testConnector) ProcessIndexMap(index IndexMap) error {
 	c.remoteHandling.RLock()
 	defer c.remoteHandling.RUnlock()
-	m := c.remoteMember[rq.RequestDestination]
-	if m != nil {
-		m.connector.(*ConnectorImpl).RequestKeysCh <- rq
-	} else {
-		log.Fatalf("Lost member ForwardDataRequest")
+	for _, m := range c.remoteMember {
+		impl := m.connector.(*ConnectorImpl)
+		select {
+		case impl.ReceiveIndexCh <- index:
+		case <-impl.Done():
+		}
+	}
+	return nil
+}
+
+func (c *testConnector) ProcessDataRequest(rq DataRequest) error {
+	items := c.localMember.GetData(rq.KeyIDPairs)
+	m, err := c.getRemote(rq.RequestSource)
+	if err != nil {
+		return err
+	}
+	impl := m.connector.(*ConnectorImpl)
+	select {
+	case impl.ReceiveDataCh <- DataResponse{Items: items, AssociatedBuildTime: rq.AssociatedBuildTime}:
+		return nil
+	case <-impl.Done():
+		return ErrStopped
+	}
+}
+
+func (c *testConnector) ForwardDataRequest(hop ID, rq DataRequest) error {
+	items, err := c.relay(context.Background(), hop, rq)
+	if err != nil {
+		return err
+	}
+	impl := c.connectorChan.(*ConnectorImpl)
+	select {
+	case impl.ReceiveDataCh <- DataResponse{Items: items, AssociatedBuildTime: rq.AssociatedBuildTime}:
+		return nil
+	case <-impl.Done():
+		return ErrStopped
+	}
+}
+
+//relay asks the neighbor hop for the items of rq, hop relays it further if it is not the destination
+func (c *testConnector) relay(ctx context.Context, hop ID, rq DataRequest) (Items, error) {
+	m, err := c.getRemote(hop)
+	if err != nil {
+		return nil, err
+	}
+	impl := m.connector.(*ConnectorImpl)
+	select {
+	case <-impl.Done():
+		return nil, ErrStopped
+	default:
+	}
+	return impl.ServeDataRequest(ctx, rq, impl.ConnectorCore.(*testConnector).relay)
+}
+
+func (c *testConnector) ForwardIndexRequest(rq IndexRequest) error {
+	m, err := c.getRemote(rq.RequestDestination)
+	if err != nil {
+		return err
+	}
+	impl := m.connector.(*ConnectorImpl)
+	select {
+	case impl.RequestIndexCh <- rq:
+		return nil
+	case <-impl.Done():
+		return ErrStopped
+	}
+}
+
+func (c *testConnector) ProcessIndexResponse(rs IndexResponse) error {
+	m, err := c.getRemote(rs.RequestSource)
+	if err != nil {
+		return err
+	}
+	impl := m.connector.(*ConnectorImpl)
+	select {
+	case impl.ReceiveIndexResponseCh <- rs:
+		return nil
+	case <-impl.Done():
+		return ErrStopped
+	}
+}
+
+func (c *testConnector) ProcessDataPush(p DataPush) error {
+	c.remoteHandling.RLock()
+	defer c.remoteHandling.RUnlock()
+	for id, m := range c.remoteMember {
+		if id == p.Origin {
+			continue
+		}
+		impl := m.connector.(*ConnectorImpl)
+		select {
+		case impl.ReceivePushCh <- p:
+		case <-impl.Done():
+		}
 	}
+	return nil
 }
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	//MerkleFanout number of children of an internal node of a MerkleTree
+	MerkleFanout = 16
+	//MerkleLeafKeys is the mean number of keys per leaf the depth of a MerkleTree is sized for
+	MerkleLeafKeys = 32
+	//MaxMerkleDepth bounds the number of levels below the root, MerkleFanout^MaxMerkleDepth leaves
+	MaxMerkleDepth = 4
+)
+
+//Hash of a MerkleTree node. The zero Hash is the hash of an empty subtree
+type Hash [sha256.Size]byte
+
+//MerkleNodeID identifies a node of a MerkleTree. The root is 0 and the children of n are n*MerkleFanout+1 to n*MerkleFanout+MerkleFanout
+type MerkleNodeID uint32
+
+//MerkleNode is the content of a node sent in an IndexResponse: Children hashes for an internal node, StampedKeys for a leaf
+type MerkleNode struct {
+	ID          MerkleNodeID
+	Leaf        bool
+	Children    []Hash
+	StampedKeys StampedKeys
+}
+
+//MerkleTree summarizes the StampedKeys of an owner. Keys are spread over the leaves by the hash of the key so that
+//two members holding the same keys build the same tree, provided they use the same depth.
+type MerkleTree struct {
+	depth     int
+	firstLeaf MerkleNodeID
+	hashes    []Hash
+	leaves    []StampedKeys
+}
+
+//MerkleDepthOf returns the depth of the tree of n keys: the lowest one that leaves about MerkleLeafKeys keys per leaf
+func MerkleDepthOf(n int) int {
+	depth, leaves := 1, MerkleFanout
+	for depth < MaxMerkleDepth && leaves*MerkleLeafKeys < n {
+		depth++
+		leaves *= MerkleFanout
+	}
+	return depth
+}
+
+//NewMerkleTree builds the tree of keys with depth levels below the root. The depth is bounded by 1 and MaxMerkleDepth
+func NewMerkleTree(keys StampedKeys, depth int) *MerkleTree {
+	if depth < 1 {
+		depth = 1
+	}
+	if depth > MaxMerkleDepth {
+		depth = MaxMerkleDepth
+	}
+	firstLeaf, nbLeaves := MerkleNodeID(0), 1
+	for d := 0; d < depth; d++ {
+		firstLeaf = firstLeaf*MerkleFanout + 1
+		nbLeaves *= MerkleFanout
+	}
+	t := &MerkleTree{
+		depth:     depth,
+		firstLeaf: firstLeaf,
+		hashes:    make([]Hash, int(firstLeaf)+nbLeaves),
+		leaves:    make([]StampedKeys, nbLeaves),
+	}
+	for _, k := range keys {
+		l := leafOf(k.Key, depth)
+		t.leaves[l] = append(t.leaves[l], k)
+	}
+	for l, leaf := range t.leaves {
+		if len(leaf) == 0 {
+			continue
+		}
+		sort.Sort(leaf)
+		h := sha256.New()
+		for _, k := range leaf {
+			h.Write([]byte(k.Key))
+			h.Write([]byte{0})
+			binary.Write(h, binary.BigEndian, k.Timestamp.UnixNano())
+		}
+		copy(t.hashes[int(firstLeaf)+l][:], h.Sum(nil))
+	}
+	for n := int(firstLeaf) - 1; n >= 0; n-- {
+		children := t.Children(MerkleNodeID(n))
+		empty := true
+		h := sha256.New()
+		for _, c := range children {
+			if c != (Hash{}) {
+				empty = false
+			}
+			h.Write(c[:])
+		}
+		if !empty {
+			copy(t.hashes[n][:], h.Sum(nil))
+		}
+	}
+	return t
+}
+
+func leafOf(k Key, depth int) int {
+	sum := sha256.Sum256([]byte(k))
+	l := 0
+	for d := 0; d < depth; d++ {
+		l = l*MerkleFanout + int(sum[d])%MerkleFanout
+	}
+	return l
+}
+
+//Root returns the hash of the root of the tree
+func (t *MerkleTree) Root() Hash {
+	return t.hashes[0]
+}
+
+//Depth returns the number of levels below the root
+func (t *MerkleTree) Depth() int {
+	return t.depth
+}
+
+//IsLeaf returns true if n is a leaf of the tree
+func (t *MerkleTree) IsLeaf(n MerkleNodeID) bool {
+	return n >= t.firstLeaf
+}
+
+//Hash returns the hash of node n
+func (t *MerkleTree) Hash(n MerkleNodeID) Hash {
+	return t.hashes[n]
+}
+
+//Children returns the hashes of the children of the internal node n
+func (t *MerkleTree) Children(n MerkleNodeID) []Hash {
+	first := int(n)*MerkleFanout + 1
+	return t.hashes[first : first+MerkleFanout]
+}
+
+//ChildID returns the ID of the i-th child of the internal node n
+func ChildID(n MerkleNodeID, i int) MerkleNodeID {
+	return n*MerkleFanout + 1 + MerkleNodeID(i)
+}
+
+//Keys returns all the keys stored under node n
+func (t *MerkleTree) Keys(n MerkleNodeID) StampedKeys {
+	if t.IsLeaf(n) {
+		return t.leaves[n-t.firstLeaf]
+	}
+	keys := StampedKeys{}
+	for i := 0; i < MerkleFanout; i++ {
+		c := ChildID(n, i)
+		if t.hashes[c] == (Hash{}) {
+			continue
+		}
+		keys = append(keys, t.Keys(c)...)
+	}
+	return keys
+}
+
+//Node returns the content of node n as sent in an IndexResponse
+func (t *MerkleTree) Node(n MerkleNodeID) MerkleNode {
+	if t.IsLeaf(n) {
+		return MerkleNode{ID: n, Leaf: true, StampedKeys: t.leaves[n-t.firstLeaf]}
+	}
+	return MerkleNode{ID: n, Children: append([]Hash{}, t.Children(n)...)}
+}
+
+//Valid returns true if n is a node of the tree
+func (t *MerkleTree) Valid(n MerkleNodeID) bool {
+	return int(n) < len(t.hashes)
+}
@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testKeys(n int, t time.Time) StampedKeys {
+	keys := StampedKeys{}
+	for i := 0; i < n; i++ {
+		keys = append(keys, StampedKey{Key: Key(fmt.Sprintf("key%d", i)), Timestamp: t})
+	}
+	return keys
+}
+
+func TestMerkleTreeRoot(t *testing.T) {
+	now := time.Now()
+	keys := testKeys(100, now)
+	reversed := StampedKeys{}
+	for i := len(keys) - 1; i >= 0; i-- {
+		reversed = append(reversed, keys[i])
+	}
+	if NewMerkleTree(keys, 2).Root() != NewMerkleTree(reversed, 2).Root() {
+		t.Fatalf("root depends on the order of the keys")
+	}
+	if NewMerkleTree(StampedKeys{}, 2).Root() != (Hash{}) {
+		t.Fatalf("root of an empty tree must be the zero Hash")
+	}
+	updated := append(StampedKeys{}, keys...)
+	updated[42].Timestamp = now.Add(time.Second)
+	if NewMerkleTree(keys, 2).Root() == NewMerkleTree(updated, 2).Root() {
+		t.Fatalf("root does not change with a timestamp")
+	}
+	if NewMerkleTree(keys, 2).Root() == NewMerkleTree(keys[1:], 2).Root() {
+		t.Fatalf("root does not change with a removed key")
+	}
+}
+
+func TestMerkleTreeDiff(t *testing.T) {
+	now := time.Now()
+	keys := testKeys(1000, now)
+	updated := append(StampedKeys{}, keys[1:]...)
+	updated[10].Timestamp = now.Add(time.Second)
+	updated = append(updated, StampedKey{Key: "new", Timestamp: now})
+
+	depth := MerkleDepthOf(len(keys))
+	local, remote := NewMerkleTree(keys, depth), NewMerkleTree(updated, depth)
+
+	// walk down the differing subtrees like processIndexResponse does
+	nodes := []MerkleNodeID{0}
+	visited := 0
+	toFetch, toDelete := KeyIDPairs{}, KeyIDPairs{}
+	for len(nodes) > 0 {
+		next := []MerkleNodeID{}
+		for _, n := range nodes {
+			visited++
+			node := remote.Node(n)
+			if node.Leaf {
+				f, d := diffKeys("M0", local.Keys(n), node.StampedKeys, time.Now())
+				toFetch = append(toFetch, f...)
+				toDelete = append(toDelete, d...)
+				continue
+			}
+			for i, h := range node.Children {
+				if local.Hash(ChildID(n, i)) != h {
+					next = append(next, ChildID(n, i))
+				}
+			}
+		}
+		nodes = next
+	}
+	if len(toFetch) != 2 || len(toDelete) != 1 || toDelete[0].Key != keys[0].Key {
+		t.Fatalf("unexpected diff: fetch %v delete %v", toFetch, toDelete)
+	}
+	if visited > 1+3*depth {
+		t.Fatalf("visited %d nodes for 3 differing keys", visited)
+	}
+}
+
+func TestMerkleTreeKeys(t *testing.T) {
+	keys := testKeys(500, time.Now())
+	tree := NewMerkleTree(keys, MerkleDepthOf(len(keys)))
+	if len(tree.Keys(0)) != len(keys) {
+		t.Fatalf("expected %d keys under the root, got %d", len(keys), len(tree.Keys(0)))
+	}
+	count := 0
+	for i := 0; i < MerkleFanout; i++ {
+		count += len(tree.Keys(ChildID(0, i)))
+	}
+	if count != len(keys) {
+		t.Fatalf("expected %d keys under the children of the root, got %d", len(keys), count)
+	}
+}
+
+func TestMerkleDepth(t *testing.T) {
+	if d := MerkleDepthOf(0); d != 1 {
+		t.Fatalf("expected a depth of 1 without keys, got %d", d)
+	}
+	if d := MerkleDepthOf(1 << 30); d != MaxMerkleDepth {
+		t.Fatalf("expected the depth to be bounded by %d, got %d", MaxMerkleDepth, d)
+	}
+
+	// a changed key ships the keys of its leaf only
+	now := time.Now()
+	keys := testKeys(100000, now)
+	depth := MerkleDepthOf(len(keys))
+	updated := append(StampedKeys{}, keys...)
+	updated[42].Timestamp = now.Add(time.Second)
+	local, remote := NewMerkleTree(keys, depth), NewMerkleTree(updated, depth)
+	n := MerkleNodeID(0)
+	for !remote.IsLeaf(n) {
+		children := remote.Children(n)
+		for i := range children {
+			if local.Hash(ChildID(n, i)) != children[i] {
+				n = ChildID(n, i)
+				break
+			}
+		}
+	}
+	if l := len(remote.Node(n).StampedKeys); l > 4*MerkleLeafKeys {
+		t.Fatalf("%d keys shipped for a changed key out of %d", l, len(keys))
+	}
+}
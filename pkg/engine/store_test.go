@@ -8,6 +8,7 @@ import (
 	"time"
 )
 
+//Store is the storage of the test members, the store.Store interface of pkg/store
 type Store interface {
 	GetMembers() []ID
 	GetIndex(id ID) Index
@@ -16,8 +17,11 @@ type Store interface {
 	MultiSet(Items)
 	MultiDelete(KeyIDPairs)
 	Get(KeyIDPair) Item
+	DeleteShard(ID)
 }
 
+//MapStore is an in memory Store for the tests of the engine: pkg/store imports the engine, its MapStore can't be
+//used here. pkg/store runs the conformance suite on its own MapStore
 type MapStore struct {
 	sync.RWMutex
 	internal      map[ID]map[Key]Item
@@ -117,6 +121,12 @@ func (m *MapStore) Get(kp KeyIDPair) Item {
 	return nil
 }
 
+func (m *MapStore) DeleteShard(id ID) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.internal, id)
+}
+
 func (m *MapStore) Dump() string {
 	m.RLock()
 	defer m.RUnlock()
@@ -2,19 +2,405 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
 
+	"github.com/dbenque/datafan/pkg/engine"
 	"github.com/dbenque/datafan/pkg/grpc/model"
 	google_protobuf "github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
+//RPCTimeout bounds every call made to a remote member
+var RPCTimeout = 5 * time.Second
+
+//RemoteMember is a member of the mesh reachable through grpc
+type RemoteMember struct {
+	id        engine.ID
+	address   string
+	codec     engine.Codec
+	conn      *grpc.ClientConn
+	ctx       context.Context
+	cancel    context.CancelFunc
+	collector model.IndexMapCollectorClient
+	requester model.DataRequestClient
+}
+
+var _ engine.Member = &RemoteMember{}
+
+//Dial prepares the connection to the member identified by id listening on address
+func Dial(id engine.ID, address string, codec engine.Codec) (*RemoteMember, error) {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	// canceled by Close to abort the calls in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RemoteMember{
+		id:        id,
+		address:   address,
+		codec:     codec,
+		conn:      conn,
+		ctx:       ctx,
+		cancel:    cancel,
+		collector: model.NewIndexMapCollectorClient(conn),
+		requester: model.NewDataRequestClient(conn),
+	}, nil
+}
+
+func (r *RemoteMember) ID() engine.ID {
+	return r.id
+}
+
+//GetIndexes always returns an empty IndexMap: remote indexes are pushed through CollectIndexMap, never pulled
+func (r *RemoteMember) GetIndexes() engine.IndexMap {
+	return engine.IndexMap{Source: r.id, Indexes: map[engine.ID]engine.Index{}}
+}
+
+func (r *RemoteMember) GetData(kps engine.KeyIDPairs) engine.Items {
+	items, err := r.getData(kps)
+	if err != nil {
+		log.Printf("GetData from %s failed: %v", r.id, err)
+		return engine.Items{}
+	}
+	return items
+}
+
+func (r *RemoteMember) getData(kps engine.KeyIDPairs) (engine.Items, error) {
+	return r.requestData(r.ctx, toModelKeyIDPairs(kps))
+}
+
+//routeData asks the member for the items of rq, it relays rq if it is not the RequestDestination.
+//The call is bounded by ctx, the one of the request relayed if any
+func (r *RemoteMember) routeData(ctx context.Context, rq engine.DataRequest) (engine.Items, error) {
+	return r.requestData(ctx, toModelDataRequest(rq))
+}
+
+func (r *RemoteMember) requestData(ctx context.Context, kps *model.KeyIDPairs) (engine.Items, error) {
+	ctx, cancel := r.callContext(ctx)
+	defer cancel()
+	m, err := r.requester.GetData(ctx, kps)
+	if err != nil {
+		return nil, err
+	}
+	return fromModelItems(r.codec, m)
+}
+
+//callContext bounds a call by RPCTimeout, by ctx and by Close
+func (r *RemoteMember) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, RPCTimeout)
+	stop := context.AfterFunc(r.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (r *RemoteMember) collectIndexMap(im *model.IndexMap) error {
+	ctx, cancel := context.WithTimeout(r.ctx, RPCTimeout)
+	defer cancel()
+	_, err := r.collector.CollectIndexMap(ctx, im)
+	return err
+}
+
+func (r *RemoteMember) collectIndexRequest(rq *model.IndexRequest) error {
+	ctx, cancel := context.WithTimeout(r.ctx, RPCTimeout)
+	defer cancel()
+	_, err := r.collector.CollectIndexRequest(ctx, rq)
+	return err
+}
+
+func (r *RemoteMember) collectIndexResponse(rs *model.IndexResponse) error {
+	ctx, cancel := context.WithTimeout(r.ctx, RPCTimeout)
+	defer cancel()
+	_, err := r.collector.CollectIndexResponse(ctx, rs)
+	return err
+}
+
+func (r *RemoteMember) collectDataPush(p *model.DataPush) error {
+	ctx, cancel := context.WithTimeout(r.ctx, RPCTimeout)
+	defer cancel()
+	_, err := r.collector.CollectDataPush(ctx, p)
+	return err
+}
+
+//callError returns ErrDisconnected if the call failed because the member was closed
+func (r *RemoteMember) callError(err error) error {
+	if r.ctx.Err() != nil {
+		return fmt.Errorf("%w: %s", engine.ErrDisconnected, r.id)
+	}
+	return err
+}
+
+//Close aborts the calls in flight and releases the underlying connection
+func (r *RemoteMember) Close() error {
+	r.cancel()
+	return r.conn.Close()
+}
+
+//============================ Connector =========================
+
 type connector struct {
+	localMember    engine.LocalMember
+	codec          engine.Codec
+	remoteHandling sync.RWMutex
+	remoteMember   map[engine.ID]*RemoteMember
+	connectorChan  engine.ConnectorChan
+}
+
+var _ engine.ConnectorCore = &connector{}
+var _ model.IndexMapCollectorServer = &connector{}
+var _ model.DataRequestServer = &connector{}
+
+func newConnectorFactory(codec engine.Codec) engine.ConnectorCoreFactory {
+	return func(localMember engine.LocalMember, connectorChan engine.ConnectorChan) engine.ConnectorCore {
+		return &connector{
+			localMember:   localMember,
+			codec:         codec,
+			remoteMember:  map[engine.ID]*RemoteMember{},
+			connectorChan: connectorChan,
+		}
+	}
+}
+
+func (c *connector) GetLocalMember() engine.LocalMember {
+	return c.localMember
+}
+
+//Connect register a RemoteMember as a destination for the local indexes and data requests
+func (c *connector) Connect(m engine.Member) error {
+	rm, ok := m.(*RemoteMember)
+	if !ok {
+		return fmt.Errorf("can't connect member %s: not a grpc RemoteMember", m.ID())
+	}
+	c.remoteHandling.Lock()
+	defer c.remoteHandling.Unlock()
+	c.remoteMember[rm.ID()] = rm
+	return nil
+}
+
+//Disconnect stops the exchanges with the member id and cancels the calls in flight toward it
+func (c *connector) Disconnect(id engine.ID) error {
+	c.remoteHandling.Lock()
+	m := c.remoteMember[id]
+	delete(c.remoteMember, id)
+	c.remoteHandling.Unlock()
+	if m == nil {
+		return nil
+	}
+	return m.Close()
+}
+
+func (c *connector) getRemote(id engine.ID) (*RemoteMember, error) {
+	c.remoteHandling.RLock()
+	defer c.remoteHandling.RUnlock()
+	m := c.remoteMember[id]
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s", engine.ErrUnknownMember, id)
+	}
+	return m, nil
+}
+
+func (c *connector) remotes() []*RemoteMember {
+	c.remoteHandling.RLock()
+	defer c.remoteHandling.RUnlock()
+	remotes := make([]*RemoteMember, 0, len(c.remoteMember))
+	for _, m := range c.remoteMember {
+		remotes = append(remotes, m)
+	}
+	return remotes
+}
+
+//fanOut calls send for each remote member in parallel and returns the failures
+func fanOut(remotes []*RemoteMember, send func(m *RemoteMember) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(remotes))
+	for i, m := range remotes {
+		wg.Add(1)
+		go func(i int, m *RemoteMember) {
+			defer wg.Done()
+			if err := send(m); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", m.ID(), m.callError(err))
+			}
+		}(i, m)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (c *connector) Peers() []engine.ID {
+	remotes := c.remotes()
+	peers := make([]engine.ID, 0, len(remotes))
+	for _, m := range remotes {
+		peers = append(peers, m.ID())
+	}
+	return peers
+}
+
+func (c *connector) ForwardIndexMap(id engine.ID, index engine.IndexMap) error {
+	m, err := c.getRemote(id)
+	if err != nil {
+		return err
+	}
+	if err := m.collectIndexMap(toModelIndexMap(index)); err != nil {
+		return fmt.Errorf("can't send index to %s: %w", m.ID(), m.callError(err))
+	}
+	return nil
+}
+
+func (c *connector) ProcessIndexMap(index engine.IndexMap) error {
+	im := toModelIndexMap(index)
+	return fanOut(c.remotes(), func(m *RemoteMember) error {
+		return m.collectIndexMap(im)
+	})
+}
+
+//ProcessDataRequest serves a request addressed to the local member. Requests coming from remote members are served by GetData
+func (c *connector) ProcessDataRequest(rq engine.DataRequest) error {
+	if rq.RequestSource != c.localMember.ID() {
+		return fmt.Errorf("can't push data to %s: remote requests are served by GetData", rq.RequestSource)
+	}
+	items := c.localMember.GetData(rq.KeyIDPairs)
+	return c.deliverData(engine.DataResponse{Items: items, AssociatedBuildTime: rq.AssociatedBuildTime})
+}
+
+func (c *connector) ForwardDataRequest(hop engine.ID, rq engine.DataRequest) error {
+	items, err := c.relay(context.Background(), hop, rq)
+	if err != nil {
+		return err
+	}
+	return c.deliverData(engine.DataResponse{Items: items, AssociatedBuildTime: rq.AssociatedBuildTime})
+}
+
+//relay asks the neighbor hop for the items of rq, hop relays it further if it is not the destination.
+//ctx is the one of the incoming GetData when rq is relayed for another member
+func (c *connector) relay(ctx context.Context, hop engine.ID, rq engine.DataRequest) (engine.Items, error) {
+	m, err := c.getRemote(hop)
+	if err != nil {
+		return nil, err
+	}
+	items, err := m.routeData(ctx, rq)
+	if err != nil {
+		return nil, fmt.Errorf("can't get data from %s: %w", m.ID(), m.callError(err))
+	}
+	return items, nil
+}
+
+//deliverData hands over the response to the engine, unless the connector is stopped
+func (c *connector) deliverData(rs engine.DataResponse) error {
+	impl := c.connectorChan.(*engine.ConnectorImpl)
+	select {
+	case impl.ReceiveDataCh <- rs:
+		return nil
+	case <-impl.Done():
+		return engine.ErrStopped
+	}
+}
+
+func (c *connector) ForwardIndexRequest(rq engine.IndexRequest) error {
+	m, err := c.getRemote(rq.RequestDestination)
+	if err != nil {
+		return err
+	}
+	if err := m.collectIndexRequest(toModelIndexRequest(rq)); err != nil {
+		return fmt.Errorf("can't send index request to %s: %w", m.ID(), m.callError(err))
+	}
+	return nil
+}
+
+func (c *connector) ProcessIndexResponse(rs engine.IndexResponse) error {
+	m, err := c.getRemote(rs.RequestSource)
+	if err != nil {
+		return err
+	}
+	if err := m.collectIndexResponse(toModelIndexResponse(rs)); err != nil {
+		return fmt.Errorf("can't send index response to %s: %w", m.ID(), m.callError(err))
+	}
+	return nil
+}
+
+//ProcessDataPush sends the push to all the remote members except its origin
+func (c *connector) ProcessDataPush(p engine.DataPush) error {
+	mp, err := toModelDataPush(c.codec, p)
+	if err != nil {
+		return fmt.Errorf("can't encode push of %s: %w", p.Origin, err)
+	}
+	remotes := []*RemoteMember{}
+	for _, m := range c.remotes() {
+		if m.ID() != p.Origin {
+			remotes = append(remotes, m)
+		}
+	}
+	return fanOut(remotes, func(m *RemoteMember) error {
+		return m.collectDataPush(mp)
+	})
+}
+
+//CollectIndexMap receive the IndexMap pushed by a remote member and hand it over to the engine
+func (c *connector) CollectIndexMap(ctx context.Context, im *model.IndexMap) (*google_protobuf.Empty, error) {
+	select {
+	case c.connectorChan.(*engine.ConnectorImpl).ReceiveIndexCh <- fromModelIndexMap(im):
+		return &google_protobuf.Empty{}, nil
+	case <-c.connectorChan.(*engine.ConnectorImpl).Done():
+		return nil, engine.ErrStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//GetData serves the items of the local member to a remote member, or relays the request toward its destination
+func (c *connector) GetData(ctx context.Context, kps *model.KeyIDPairs) (*model.Items, error) {
+	found, err := c.connectorChan.(*engine.ConnectorImpl).ServeDataRequest(ctx, fromModelDataRequest(kps), c.relay)
+	if err != nil {
+		return nil, err
+	}
+	items, err := toModelItems(c.codec, found)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode items: %v", err)
+	}
+	return items, nil
+}
+
+//CollectIndexRequest receive an IndexRequest from a remote member, the engine answers it
+func (c *connector) CollectIndexRequest(ctx context.Context, rq *model.IndexRequest) (*google_protobuf.Empty, error) {
+	select {
+	case c.connectorChan.(*engine.ConnectorImpl).ReceiveIndexRequestCh <- fromModelIndexRequest(rq):
+		return &google_protobuf.Empty{}, nil
+	case <-c.connectorChan.(*engine.ConnectorImpl).Done():
+		return nil, engine.ErrStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-//CollectIndexMap receive the
-func (c *connector) CollectIndexMap(context.Context, *model.IndexMap) (*google_protobuf.Empty, error) {
-	return nil, nil
+//CollectIndexResponse receive the response of a remote member to an IndexRequest of the local member
+func (c *connector) CollectIndexResponse(ctx context.Context, rs *model.IndexResponse) (*google_protobuf.Empty, error) {
+	select {
+	case c.connectorChan.(*engine.ConnectorImpl).ReceiveIndexResponseCh <- fromModelIndexResponse(rs):
+		return &google_protobuf.Empty{}, nil
+	case <-c.connectorChan.(*engine.ConnectorImpl).Done():
+		return nil, engine.ErrStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (c *connector) GetData(context.Context, *model.KeyIDPairs) (*model.Items, error) {
-	return nil, nil
+//CollectDataPush receive the items pushed by a neighbor, the engine stores and relays them
+func (c *connector) CollectDataPush(ctx context.Context, mp *model.DataPush) (*google_protobuf.Empty, error) {
+	p, err := fromModelDataPush(c.codec, mp)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode items: %v", err)
+	}
+	select {
+	case c.connectorChan.(*engine.ConnectorImpl).ReceivePushCh <- p:
+		return &google_protobuf.Empty{}, nil
+	case <-c.connectorChan.(*engine.ConnectorImpl).Done():
+		return nil, engine.ErrStopped
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
@@ -0,0 +1,345 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dbenque/datafan/pkg/engine"
+	"github.com/dbenque/datafan/pkg/store"
+	"github.com/dbenque/datafan/pkg/store/storetest"
+)
+
+func startServers(t *testing.T, N int) ([]*Server, []string) {
+	servers := make([]*Server, N)
+	addresses := make([]string, N)
+	for i := range servers {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		servers[i] = NewServer(fmt.Sprintf("M%d", i), store.NewMapStore(), storetest.Codec{})
+		addresses[i] = lis.Addr().String()
+		go servers[i].Serve(lis)
+	}
+	return servers, addresses
+}
+
+func connect(t *testing.T, e *engine.Engine, from *Server, to *Server, address string) {
+	rm, err := from.Dial(string(to.ID()), address)
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", to.ID(), err)
+	}
+	if err := e.AddMember(rm); err != nil {
+		t.Fatalf("failed to connect %s: %v", to.ID(), err)
+	}
+}
+
+func waitForCount(t *testing.T, servers []*Server, count int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for _, s := range servers {
+		for s.GetStorage().(*store.MapStore).Count() != count {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s has %d items, expected %d", s.ID(), s.GetStorage().(*store.MapStore).Count(), count)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+//startLine runs N engines connected in line, they are stopped at the end of the test
+func startLine(t *testing.T, N int, syncPeriod time.Duration, options ...engine.Option) ([]*Server, []*engine.Engine) {
+	servers, addresses := startServers(t, N)
+	engines := make([]*engine.Engine, N)
+	for i := range servers {
+		engines[i] = engine.NewEngine(servers[i], syncPeriod, options...)
+	}
+	// grpc connections are one way: connect both ends of each link
+	for i := 1; i < N; i++ {
+		connect(t, engines[i], servers[i], servers[i-1], addresses[i-1])
+		connect(t, engines[i-1], servers[i-1], servers[i], addresses[i])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, N)
+	t.Cleanup(func() {
+		cancel()
+		for range engines {
+			if err := <-errs; err != nil {
+				t.Errorf("engine shutdown: %v", err)
+			}
+		}
+		for _, s := range servers {
+			s.Stop()
+		}
+	})
+	for _, e := range engines {
+		go func(e *engine.Engine) { errs <- e.Run(ctx) }(e)
+	}
+	return servers, engines
+}
+
+func TestConnectorLine(t *testing.T) {
+	testConnectorLine(t)
+}
+
+func TestConnectorLineMerkle(t *testing.T) {
+	testConnectorLine(t, engine.WithIndexMode(engine.IndexModeMerkle))
+}
+
+func TestConnectorLineDelta(t *testing.T) {
+	testConnectorLine(t, engine.WithIndexMode(engine.IndexModeDelta))
+}
+
+func testConnectorLine(t *testing.T, options ...engine.Option) {
+	N := 4
+	servers, _ := startLine(t, N, 20*time.Millisecond, options...)
+
+	for i, s := range servers {
+		if err := s.Write(storetest.NewItem(s.ID(), "k", fmt.Sprintf("v%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForCount(t, servers, N, 5*time.Second)
+
+	if err := servers[0].Write(storetest.NewItem(servers[0].ID(), "k", "updated")); err != nil {
+		t.Fatal(err)
+	}
+	kp := engine.KeyIDPair{Key: "k", ID: servers[0].ID()}
+	deadline := time.Now().Add(5 * time.Second)
+	for _, s := range servers {
+		for {
+			if i := s.GetData(engine.KeyIDPairs{kp}); len(i) == 1 && i[0].(*storetest.Item).Value == "updated" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("update not received by %s", s.ID())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	servers[N-1].Remove("k")
+	waitForCount(t, servers, N-1, 5*time.Second)
+}
+
+func TestConnectorPush(t *testing.T) {
+	N := 4
+	// the index sync never ticks: only the push can propagate the write
+	servers, _ := startLine(t, N, time.Hour, engine.WithPush(N))
+
+	item := storetest.NewItem(servers[0].ID(), "k", "v")
+	if err := servers[0].Write(item); err != nil {
+		t.Fatal(err)
+	}
+	waitForCount(t, servers, 1, 5*time.Second)
+}
+
+func TestConnectorLeave(t *testing.T) {
+	servers, engines := startLine(t, 3, 20*time.Millisecond)
+
+	if err := engines[0].Leave(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for i, e := range engines[1:] {
+		for {
+			if _, ok := e.Departed()[servers[0].ID()]; ok {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s did not receive the leave announcement", servers[i+1].ID())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	core := servers[1].GetConnector().(*engine.ConnectorImpl).ConnectorCore.(*connector)
+	if _, err := core.getRemote(servers[0].ID()); err == nil {
+		t.Fatalf("%s is still connected to the departed member", servers[1].ID())
+	}
+}
+
+func TestConnectorRetire(t *testing.T) {
+	N := 3
+	servers, engines := startLine(t, N, 20*time.Millisecond)
+	for _, s := range servers {
+		if err := s.Write(storetest.NewItem(s.ID(), "k", "v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForCount(t, servers, N, 5*time.Second)
+
+	engines[0].Retire()
+	waitForCount(t, servers, N-1, 5*time.Second)
+	for i, e := range engines[1:] {
+		if _, ok := e.Retired()[servers[0].ID()]; !ok {
+			t.Fatalf("%s does not know the retirement", servers[i+1].ID())
+		}
+	}
+}
+
+func TestConnectorInterest(t *testing.T) {
+	servers, engines := startLine(t, 3, 20*time.Millisecond)
+	engines[2].SetInterest(engine.Interest{Owners: []engine.ID{servers[0].ID()}, KeyPrefixes: []engine.Key{"user/"}})
+	for _, k := range []engine.Key{"user/k", "group/k"} {
+		if err := servers[0].Write(storetest.NewItem(servers[0].ID(), k, "v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := servers[1].Write(storetest.NewItem(servers[1].ID(), "user/k", "v")); err != nil {
+		t.Fatal(err)
+	}
+	waitForCount(t, servers[:2], 3, 5*time.Second)
+	waitForCount(t, servers[2:], 1, 5*time.Second)
+	time.Sleep(200 * time.Millisecond)
+	if c := servers[2].GetStorage().(*store.MapStore).Count(); c != 1 {
+		t.Fatalf("%s stores %d items, expected 1", servers[2].ID(), c)
+	}
+}
+
+func TestConnectorExpiry(t *testing.T) {
+	servers, _ := startLine(t, 3, 20*time.Millisecond)
+	session := storetest.NewItem(servers[0].ID(), "session", "v")
+	session.Expires = time.Now().Add(500 * time.Millisecond)
+	if err := servers[0].Write(session); err != nil {
+		t.Fatal(err)
+	}
+	if err := servers[0].Write(storetest.NewItem(servers[0].ID(), "k", "v")); err != nil {
+		t.Fatal(err)
+	}
+	waitForCount(t, servers, 2, 5*time.Second)
+	waitForCount(t, servers, 1, 5*time.Second)
+}
+
+func TestConnectorTransfer(t *testing.T) {
+	servers, engines := startLine(t, 3, 20*time.Millisecond)
+	if err := servers[0].Write(storetest.NewItem(servers[0].ID(), "k", "v")); err != nil {
+		t.Fatal(err)
+	}
+	waitForCount(t, servers, 1, 5*time.Second)
+
+	if err := engines[0].Transfer(servers[2].ID(), nil); err != nil {
+		t.Fatal(err)
+	}
+	kp := engine.KeyIDPair{Key: "k", ID: servers[2].ID()}
+	deadline := time.Now().Add(5 * time.Second)
+	for _, s := range servers {
+		for len(s.GetData(engine.KeyIDPairs{kp})) != 1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s did not move the item to the new owner", s.ID())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitForCount(t, servers, 1, 5*time.Second)
+}
+
+func TestConnectorConverged(t *testing.T) {
+	servers, engines := startLine(t, 3, 20*time.Millisecond)
+	if err := servers[0].Write(storetest.NewItem(servers[0].ID(), "k", "v")); err != nil {
+		t.Fatal(err)
+	}
+	waitForCount(t, servers, 1, 5*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for engines[0].Digest(servers[0].ID()).BuildTime.IsZero() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	buildTime := engines[0].Digest(servers[0].ID()).BuildTime
+	if err := engines[1].WaitConverged(ctx, servers[0].ID(), buildTime); err != nil {
+		t.Fatal(err)
+	}
+	if same, err := engines[1].SameView(servers[2].ID(), servers[0].ID()); err != nil || !same {
+		t.Fatalf("the digest of %s differs: %v", servers[2].ID(), err)
+	}
+}
+
+func TestConnectorBarrier(t *testing.T) {
+	servers, engines := startLine(t, 3, 20*time.Millisecond)
+	item := storetest.NewItem(servers[1].ID(), "k", "v")
+	if err := servers[1].Write(item); err != nil {
+		t.Fatal(err)
+	}
+	b, err := engines[1].Barrier(context.Background(), item.StampedKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Wait(context.Background(), 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*Server{servers[0], servers[2]} {
+		if len(s.GetData(engine.KeyIDPairs{{ID: servers[1].ID(), Key: "k"}})) != 1 {
+			t.Fatalf("the barrier resolved before %s received the write", s.ID())
+		}
+	}
+}
+
+func TestConnectorMetrics(t *testing.T) {
+	servers, _ := startLine(t, 2, 20*time.Millisecond)
+	if err := servers[0].Write(storetest.NewItem(servers[0].ID(), "k", "v")); err != nil {
+		t.Fatal(err)
+	}
+	waitForCount(t, servers, 1, 5*time.Second)
+	m := servers[0].Metrics()
+	if m.Workers != engine.DefaultConnectorWorkers || m.Peers[servers[1].ID()].Sent == 0 {
+		t.Fatalf("unexpected metrics %+v", m)
+	}
+}
+
+func TestWriteNotOwned(t *testing.T) {
+	s := NewServer("M0", store.NewMapStore(), storetest.Codec{})
+	if err := s.Write(storetest.NewItem("M1", "k", "v")); err == nil {
+		t.Fatalf("expected an error when writing an item owned by another member")
+	}
+}
+
+func TestWriteStamped(t *testing.T) {
+	s := NewServer("M0", store.NewMapStore(), storetest.Codec{})
+	// the caller clock is an hour ahead
+	item := storetest.NewItem("M0", "k", "v")
+	item.Time = time.Now().Add(time.Hour)
+	if err := s.Write(item); err != nil {
+		t.Fatal(err)
+	}
+	stamp := s.GetStorage().Get(engine.KeyIDPair{ID: "M0", Key: "k"}).StampedKey().Timestamp
+	if !stamp.Before(item.Time) || !s.Clock().Now().After(stamp) {
+		t.Fatalf("the item was not stamped by the clock of the server: %v", stamp)
+	}
+}
+
+func TestForwardToUnknownMember(t *testing.T) {
+	s := NewServer("M0", store.NewMapStore(), storetest.Codec{})
+	core := s.GetConnector().(*engine.ConnectorImpl).ConnectorCore
+	err := core.ForwardDataRequest("M1", engine.DataRequest{RequestSource: "M0", RequestDestination: "M1"})
+	if !errors.Is(err, engine.ErrUnknownMember) {
+		t.Fatalf("expected ErrUnknownMember, got %v", err)
+	}
+}
+
+func TestConnectorRouting(t *testing.T) {
+	// no sync: the item stays on M0
+	servers, _ := startLine(t, 3, time.Hour)
+	if err := servers[0].Write(storetest.NewItem(servers[0].ID(), "k", "v")); err != nil {
+		t.Fatal(err)
+	}
+	kp := engine.KeyIDPair{Key: "k", ID: servers[0].ID()}
+	if len(servers[1].GetData(engine.KeyIDPairs{kp})) != 0 {
+		t.Fatalf("the item was replicated to M1")
+	}
+
+	core := servers[2].GetConnector().(*engine.ConnectorImpl).ConnectorCore.(*connector)
+	items, err := core.relay(context.Background(), servers[1].ID(), engine.DataRequest{RequestSource: "M2", RequestDestination: "M0", KeyIDPairs: engine.KeyIDPairs{kp}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].(*storetest.Item).Value != "v" {
+		t.Fatalf("the request was not relayed to M0: %v", items)
+	}
+	if _, err := core.relay(context.Background(), servers[1].ID(), engine.DataRequest{RequestSource: "M2", RequestDestination: "M9", KeyIDPairs: engine.KeyIDPairs{kp}}); err == nil {
+		t.Fatalf("expected an error toward an unknown member")
+	}
+}
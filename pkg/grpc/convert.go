@@ -0,0 +1,411 @@
+package grpc
+
+import (
+	"time"
+
+	"github.com/dbenque/datafan/pkg/engine"
+	"github.com/dbenque/datafan/pkg/grpc/model"
+	"github.com/golang/protobuf/ptypes"
+	google_protobuf "github.com/golang/protobuf/ptypes/timestamp"
+)
+
+func toProtoTime(t time.Time) *google_protobuf.Timestamp {
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return &google_protobuf.Timestamp{}
+	}
+	return ts
+}
+
+//toProtoExpires leaves unset the expiration of the items that never expire
+func toProtoExpires(t time.Time) *google_protobuf.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return toProtoTime(t)
+}
+
+func fromProtoTime(ts *google_protobuf.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func toModelIndexMap(im engine.IndexMap) *model.IndexMap {
+	m := &model.IndexMap{
+		Source:    string(im.Source),
+		Indexes:   map[string]*model.Index{},
+		Clock:     toProtoTime(im.Clock),
+		Interests: map[string]*model.MemberInterest{},
+	}
+	for id, mi := range im.Interests {
+		m.Interests[string(id)] = &model.MemberInterest{
+			Interest: toModelInterest(mi.Interest),
+			Version:  toProtoTime(mi.Version),
+			Via:      string(mi.Via),
+		}
+	}
+	// a set Scope is never empty: no Scope is read as nil
+	for _, interest := range im.Scope {
+		m.Scope = append(m.Scope, toModelInterest(interest))
+	}
+	for _, h := range im.Handovers {
+		m.Handovers = append(m.Handovers, &model.Handover{
+			From: string(h.From),
+			To:   string(h.To),
+			Time: toProtoTime(h.Time),
+			Keys: toModelStampedKeys(h.Keys),
+		})
+	}
+	for id, index := range im.Indexes {
+		mi := &model.Index{
+			BuildTime:    toProtoTime(index.BuildTime),
+			StamptedKeys: toModelStampedKeys(index.StampedKeys),
+			Mode:         model.IndexMode(index.Mode),
+			Retired:      index.Retired,
+		}
+		if index.Mode == engine.IndexModeMerkle {
+			mi.MerkleRoot = index.MerkleRoot[:]
+			mi.MerkleDepth = int32(index.MerkleDepth)
+		}
+		if index.Digest != (engine.Hash{}) {
+			mi.Digest = index.Digest[:]
+		}
+		m.Indexes[string(id)] = mi
+	}
+	return m
+}
+
+func fromModelIndexMap(m *model.IndexMap) engine.IndexMap {
+	im := engine.IndexMap{
+		Source:  engine.ID(m.GetSource()),
+		Indexes: map[engine.ID]engine.Index{},
+		Clock:   fromProtoTime(m.GetClock()),
+	}
+	if len(m.GetInterests()) > 0 {
+		im.Interests = map[engine.ID]engine.MemberInterest{}
+		for id, mi := range m.GetInterests() {
+			im.Interests[engine.ID(id)] = engine.MemberInterest{
+				Interest: fromModelInterest(mi.GetInterest()),
+				Version:  fromProtoTime(mi.GetVersion()),
+				Via:      engine.ID(mi.GetVia()),
+			}
+		}
+	}
+	for _, interest := range m.GetScope() {
+		im.Scope = append(im.Scope, fromModelInterest(interest))
+	}
+	for _, h := range m.GetHandovers() {
+		im.Handovers = append(im.Handovers, engine.Handover{
+			From: engine.ID(h.GetFrom()),
+			To:   engine.ID(h.GetTo()),
+			Time: fromProtoTime(h.GetTime()),
+			Keys: fromModelStampedKeys(h.GetKeys()),
+		})
+	}
+	for id, mi := range m.GetIndexes() {
+		index := engine.Index{
+			BuildTime:   fromProtoTime(mi.GetBuildTime()),
+			StampedKeys: fromModelStampedKeys(mi.GetStamptedKeys()),
+			Mode:        engine.IndexMode(mi.GetMode()),
+			MerkleDepth: int(mi.GetMerkleDepth()),
+			Retired:     mi.GetRetired(),
+		}
+		copy(index.MerkleRoot[:], mi.GetMerkleRoot())
+		copy(index.Digest[:], mi.GetDigest())
+		im.Indexes[engine.ID(id)] = index
+	}
+	return im
+}
+
+//toModelInterest converts the advertised part of an Interest, the predicate is local
+func toModelInterest(i engine.Interest) *model.Interest {
+	m := &model.Interest{}
+	for _, id := range i.Owners {
+		m.Owners = append(m.Owners, string(id))
+	}
+	for _, p := range i.KeyPrefixes {
+		m.KeyPrefixes = append(m.KeyPrefixes, string(p))
+	}
+	return m
+}
+
+func fromModelInterest(m *model.Interest) engine.Interest {
+	i := engine.Interest{}
+	for _, id := range m.GetOwners() {
+		i.Owners = append(i.Owners, engine.ID(id))
+	}
+	for _, p := range m.GetKeyPrefixes() {
+		i.KeyPrefixes = append(i.KeyPrefixes, engine.Key(p))
+	}
+	return i
+}
+
+func toModelStampedKeys(sks engine.StampedKeys) []*model.StampedKey {
+	m := make([]*model.StampedKey, len(sks))
+	for i, sk := range sks {
+		m[i] = &model.StampedKey{Key: string(sk.Key), Timestamp: toProtoTime(sk.Timestamp), Expires: toProtoExpires(sk.Expires)}
+	}
+	return m
+}
+
+func fromModelStampedKeys(m []*model.StampedKey) engine.StampedKeys {
+	sks := make(engine.StampedKeys, len(m))
+	for i, sk := range m {
+		sks[i] = engine.StampedKey{Key: engine.Key(sk.GetKey()), Timestamp: fromProtoTime(sk.GetTimestamp()), Expires: fromProtoTime(sk.GetExpires())}
+	}
+	return sks
+}
+
+func toModelHashes(hashes map[engine.ID]engine.Hash) map[string][]byte {
+	m := map[string][]byte{}
+	for id, h := range hashes {
+		h := h
+		m[string(id)] = h[:]
+	}
+	return m
+}
+
+func fromModelHashes(m map[string][]byte) map[engine.ID]engine.Hash {
+	hashes := map[engine.ID]engine.Hash{}
+	for id, b := range m {
+		var h engine.Hash
+		copy(h[:], b)
+		hashes[engine.ID(id)] = h
+	}
+	return hashes
+}
+
+func toModelIndexRequest(rq engine.IndexRequest) *model.IndexRequest {
+	m := &model.IndexRequest{
+		RequestSource:      string(rq.RequestSource),
+		RequestDestination: string(rq.RequestDestination),
+		MerkleNodes:        map[string]*model.MerkleNodeIDs{},
+		MerkleRoots:        toModelHashes(rq.MerkleRoots),
+		Since:              map[string]*google_protobuf.Timestamp{},
+	}
+	for id, t := range rq.Since {
+		m.Since[string(id)] = toProtoTime(t)
+	}
+	for id, nodes := range rq.MerkleNodes {
+		ids := &model.MerkleNodeIDs{Ids: make([]uint32, len(nodes))}
+		for i, n := range nodes {
+			ids.Ids[i] = uint32(n)
+		}
+		m.MerkleNodes[string(id)] = ids
+	}
+	return m
+}
+
+func fromModelIndexRequest(m *model.IndexRequest) engine.IndexRequest {
+	rq := engine.IndexRequest{
+		RequestSource:      engine.ID(m.GetRequestSource()),
+		RequestDestination: engine.ID(m.GetRequestDestination()),
+		MerkleNodes:        map[engine.ID][]engine.MerkleNodeID{},
+		MerkleRoots:        fromModelHashes(m.GetMerkleRoots()),
+		Since:              map[engine.ID]time.Time{},
+	}
+	for id, t := range m.GetSince() {
+		rq.Since[engine.ID(id)] = fromProtoTime(t)
+	}
+	for id, ids := range m.GetMerkleNodes() {
+		nodes := make([]engine.MerkleNodeID, len(ids.GetIds()))
+		for i, n := range ids.GetIds() {
+			nodes[i] = engine.MerkleNodeID(n)
+		}
+		rq.MerkleNodes[engine.ID(id)] = nodes
+	}
+	return rq
+}
+
+func toModelIndexResponse(rs engine.IndexResponse) *model.IndexResponse {
+	m := &model.IndexResponse{
+		RequestSource: string(rs.RequestSource),
+		Source:        string(rs.Source),
+		BuildTime:     map[string]*google_protobuf.Timestamp{},
+		MerkleNodes:   map[string]*model.MerkleNodes{},
+		MerkleRoots:   toModelHashes(rs.MerkleRoots),
+		MerkleDepths:  map[string]int32{},
+		Deltas:        map[string]*model.IndexDelta{},
+	}
+	for id, d := range rs.MerkleDepths {
+		m.MerkleDepths[string(id)] = int32(d)
+	}
+	for id, d := range rs.Deltas {
+		deleted := make([]string, len(d.Deleted))
+		for i, k := range d.Deleted {
+			deleted[i] = string(k)
+		}
+		m.Deltas[string(id)] = &model.IndexDelta{
+			Since:       toProtoTime(d.Since),
+			Full:        d.Full,
+			StampedKeys: toModelStampedKeys(d.StampedKeys),
+			Deleted:     deleted,
+		}
+	}
+	for id, t := range rs.BuildTime {
+		m.BuildTime[string(id)] = toProtoTime(t)
+	}
+	for id, nodes := range rs.MerkleNodes {
+		mn := &model.MerkleNodes{Nodes: make([]*model.MerkleNode, len(nodes))}
+		for i, n := range nodes {
+			children := make([][]byte, len(n.Children))
+			for c := range n.Children {
+				children[c] = n.Children[c][:]
+			}
+			mn.Nodes[i] = &model.MerkleNode{
+				Id:          uint32(n.ID),
+				Leaf:        n.Leaf,
+				Children:    children,
+				StampedKeys: toModelStampedKeys(n.StampedKeys),
+			}
+		}
+		m.MerkleNodes[string(id)] = mn
+	}
+	return m
+}
+
+func fromModelIndexResponse(m *model.IndexResponse) engine.IndexResponse {
+	rs := engine.IndexResponse{
+		RequestSource: engine.ID(m.GetRequestSource()),
+		Source:        engine.ID(m.GetSource()),
+		BuildTime:     map[engine.ID]time.Time{},
+		MerkleNodes:   map[engine.ID][]engine.MerkleNode{},
+		MerkleRoots:   fromModelHashes(m.GetMerkleRoots()),
+		MerkleDepths:  map[engine.ID]int{},
+		Deltas:        map[engine.ID]engine.IndexDelta{},
+	}
+	for id, d := range m.GetMerkleDepths() {
+		rs.MerkleDepths[engine.ID(id)] = int(d)
+	}
+	for id, d := range m.GetDeltas() {
+		deleted := make(engine.Keys, len(d.GetDeleted()))
+		for i, k := range d.GetDeleted() {
+			deleted[i] = engine.Key(k)
+		}
+		rs.Deltas[engine.ID(id)] = engine.IndexDelta{
+			Since:       fromProtoTime(d.GetSince()),
+			Full:        d.GetFull(),
+			StampedKeys: fromModelStampedKeys(d.GetStampedKeys()),
+			Deleted:     deleted,
+		}
+	}
+	for id, t := range m.GetBuildTime() {
+		rs.BuildTime[engine.ID(id)] = fromProtoTime(t)
+	}
+	for id, mn := range m.GetMerkleNodes() {
+		nodes := make([]engine.MerkleNode, len(mn.GetNodes()))
+		for i, n := range mn.GetNodes() {
+			nodes[i] = engine.MerkleNode{
+				ID:          engine.MerkleNodeID(n.GetId()),
+				Leaf:        n.GetLeaf(),
+				Children:    make([]engine.Hash, len(n.GetChildren())),
+				StampedKeys: fromModelStampedKeys(n.GetStampedKeys()),
+			}
+			for c, h := range n.GetChildren() {
+				copy(nodes[i].Children[c][:], h)
+			}
+		}
+		rs.MerkleNodes[engine.ID(id)] = nodes
+	}
+	return rs
+}
+
+func toModelKeyIDPairs(kps engine.KeyIDPairs) *model.KeyIDPairs {
+	m := &model.KeyIDPairs{KeyIDPairs: make([]*model.KeyIDPair, len(kps))}
+	for i, kp := range kps {
+		m.KeyIDPairs[i] = &model.KeyIDPair{Key: string(kp.Key), Id: string(kp.ID)}
+	}
+	return m
+}
+
+func toModelDataRequest(rq engine.DataRequest) *model.KeyIDPairs {
+	m := toModelKeyIDPairs(rq.KeyIDPairs)
+	m.Source = string(rq.RequestSource)
+	m.Destination = string(rq.RequestDestination)
+	m.Hops = int32(rq.Hops)
+	return m
+}
+
+func fromModelDataRequest(m *model.KeyIDPairs) engine.DataRequest {
+	return engine.DataRequest{
+		RequestSource:      engine.ID(m.GetSource()),
+		RequestDestination: engine.ID(m.GetDestination()),
+		KeyIDPairs:         fromModelKeyIDPairs(m),
+		Hops:               int(m.GetHops()),
+	}
+}
+
+func fromModelKeyIDPairs(m *model.KeyIDPairs) engine.KeyIDPairs {
+	kps := make(engine.KeyIDPairs, len(m.GetKeyIDPairs()))
+	for i, kp := range m.GetKeyIDPairs() {
+		kps[i] = engine.KeyIDPair{Key: engine.Key(kp.GetKey()), ID: engine.ID(kp.GetId())}
+	}
+	return kps
+}
+
+func toModelItems(codec engine.Codec, items engine.Items) (*model.Items, error) {
+	m := &model.Items{Items: make([]*model.Item, 0, len(items))}
+	for _, i := range items {
+		data, err := codec.Marshal(i)
+		if err != nil {
+			return nil, err
+		}
+		sk := i.StampedKey()
+		m.Items = append(m.Items, &model.Item{
+			Data:      data,
+			Key:       string(sk.Key),
+			Timestamp: toProtoTime(sk.Timestamp),
+			Owner:     string(i.OwnedBy()),
+			Expires:   toProtoExpires(sk.Expires),
+		})
+	}
+	return m, nil
+}
+
+func fromModelItems(codec engine.Codec, m *model.Items) (engine.Items, error) {
+	items := make(engine.Items, 0, len(m.GetItems()))
+	for _, mi := range m.GetItems() {
+		i, err := codec.Unmarshal(mi.GetData())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, nil
+}
+
+func toModelDataPush(codec engine.Codec, p engine.DataPush) (*model.DataPush, error) {
+	items, err := toModelItems(codec, p.Items)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DataPush{
+		Origin:   string(p.Origin),
+		Sequence: p.Sequence,
+		Sender:   string(p.Sender),
+		Hops:     int32(p.Hops),
+		Items:    items,
+		Leave:    p.Leave,
+	}, nil
+}
+
+func fromModelDataPush(codec engine.Codec, m *model.DataPush) (engine.DataPush, error) {
+	items, err := fromModelItems(codec, m.GetItems())
+	if err != nil {
+		return engine.DataPush{}, err
+	}
+	return engine.DataPush{
+		Origin:   engine.ID(m.GetOrigin()),
+		Sequence: m.GetSequence(),
+		Sender:   engine.ID(m.GetSender()),
+		Hops:     int(m.GetHops()),
+		Items:    items,
+		Leave:    m.GetLeave(),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package grpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dbenque/datafan/pkg/engine"
+	"github.com/dbenque/datafan/pkg/grpc/model"
+	"github.com/golang/protobuf/proto"
+)
+
+//roundTripIndexMap serializes the IndexMap like CollectIndexMap does and reads it back
+func roundTripIndexMap(t *testing.T, im engine.IndexMap) engine.IndexMap {
+	t.Helper()
+	data, err := proto.Marshal(toModelIndexMap(im))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &model.IndexMap{}
+	if err := proto.Unmarshal(data, m); err != nil {
+		t.Fatal(err)
+	}
+	return fromModelIndexMap(m)
+}
+
+func TestConvertIndexMapScope(t *testing.T) {
+	now := time.Now()
+	im := engine.IndexMap{
+		Source:  "M0",
+		Indexes: map[engine.ID]engine.Index{"M0": {BuildTime: now, StampedKeys: engine.StampedKeys{{Key: "k", Timestamp: now}}}},
+	}
+	if scope := roundTripIndexMap(t, im).Scope; scope != nil {
+		t.Fatalf("an IndexMap without Scope is read as pruned: %v", scope)
+	}
+
+	im.Scope = []engine.Interest{{Owners: []engine.ID{"M1"}}, {Owners: []engine.ID{"M0"}, KeyPrefixes: []engine.Key{"user/"}}}
+	if scope := roundTripIndexMap(t, im).Scope; !reflect.DeepEqual(scope, im.Scope) {
+		t.Fatalf("expected the scope %v, got %v", im.Scope, scope)
+	}
+}
@@ -14,7 +14,17 @@ It has these top-level messages:
 	KeyIDPairs
 	StampedKey
 	Index
+	Interest
+	MemberInterest
+	Handover
 	IndexMap
+	MerkleNode
+	MerkleNodes
+	MerkleNodeIDs
+	IndexDelta
+	IndexRequest
+	IndexResponse
+	DataPush
 */
 package model
 
@@ -40,11 +50,36 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type IndexMode int32
+
+const (
+	IndexMode_FULL   IndexMode = 0
+	IndexMode_MERKLE IndexMode = 1
+	IndexMode_DELTA  IndexMode = 2
+)
+
+var IndexMode_name = map[int32]string{
+	0: "FULL",
+	1: "MERKLE",
+	2: "DELTA",
+}
+var IndexMode_value = map[string]int32{
+	"FULL":   0,
+	"MERKLE": 1,
+	"DELTA":  2,
+}
+
+func (x IndexMode) String() string {
+	return proto.EnumName(IndexMode_name, int32(x))
+}
+func (IndexMode) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
 type Item struct {
 	Data      []byte                     `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
 	Key       string                     `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
 	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=timestamp" json:"timestamp,omitempty"`
 	Owner     string                     `protobuf:"bytes,4,opt,name=owner" json:"owner,omitempty"`
+	Expires   *google_protobuf.Timestamp `protobuf:"bytes,5,opt,name=expires" json:"expires,omitempty"`
 }
 
 func (m *Item) Reset()                    { *m = Item{} }
@@ -80,6 +115,13 @@ func (m *Item) GetOwner() string {
 	return ""
 }
 
+func (m *Item) GetExpires() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
 type Items struct {
 	Items []*Item `protobuf:"bytes,1,rep,name=items" json:"items,omitempty"`
 }
@@ -121,7 +163,10 @@ func (m *KeyIDPair) GetId() string {
 }
 
 type KeyIDPairs struct {
-	KeyIDPairs []*KeyIDPair `protobuf:"bytes,1,rep,name=keyIDPairs" json:"keyIDPairs,omitempty"`
+	KeyIDPairs  []*KeyIDPair `protobuf:"bytes,1,rep,name=keyIDPairs" json:"keyIDPairs,omitempty"`
+	Source      string       `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	Destination string       `protobuf:"bytes,3,opt,name=destination" json:"destination,omitempty"`
+	Hops        int32        `protobuf:"varint,4,opt,name=hops" json:"hops,omitempty"`
 }
 
 func (m *KeyIDPairs) Reset()                    { *m = KeyIDPairs{} }
@@ -136,9 +181,31 @@ func (m *KeyIDPairs) GetKeyIDPairs() []*KeyIDPair {
 	return nil
 }
 
+func (m *KeyIDPairs) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *KeyIDPairs) GetDestination() string {
+	if m != nil {
+		return m.Destination
+	}
+	return ""
+}
+
+func (m *KeyIDPairs) GetHops() int32 {
+	if m != nil {
+		return m.Hops
+	}
+	return 0
+}
+
 type StampedKey struct {
 	Key       string                     `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
 	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=timestamp" json:"timestamp,omitempty"`
+	Expires   *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=expires" json:"expires,omitempty"`
 }
 
 func (m *StampedKey) Reset()                    { *m = StampedKey{} }
@@ -160,9 +227,21 @@ func (m *StampedKey) GetTimestamp() *google_protobuf.Timestamp {
 	return nil
 }
 
+func (m *StampedKey) GetExpires() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
 type Index struct {
 	BuildTime    *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=buildTime" json:"buildTime,omitempty"`
 	StamptedKeys []*StampedKey              `protobuf:"bytes,2,rep,name=stamptedKeys" json:"stamptedKeys,omitempty"`
+	Mode         IndexMode                  `protobuf:"varint,3,opt,name=mode,enum=model.IndexMode" json:"mode,omitempty"`
+	MerkleRoot   []byte                     `protobuf:"bytes,4,opt,name=merkleRoot,proto3" json:"merkleRoot,omitempty"`
+	Retired      bool                       `protobuf:"varint,5,opt,name=retired" json:"retired,omitempty"`
+	Digest       []byte                     `protobuf:"bytes,6,opt,name=digest,proto3" json:"digest,omitempty"`
+	MerkleDepth  int32                      `protobuf:"varint,7,opt,name=merkleDepth" json:"merkleDepth,omitempty"`
 }
 
 func (m *Index) Reset()                    { *m = Index{} }
@@ -184,15 +263,150 @@ func (m *Index) GetStamptedKeys() []*StampedKey {
 	return nil
 }
 
+func (m *Index) GetMode() IndexMode {
+	if m != nil {
+		return m.Mode
+	}
+	return IndexMode_FULL
+}
+
+func (m *Index) GetMerkleRoot() []byte {
+	if m != nil {
+		return m.MerkleRoot
+	}
+	return nil
+}
+
+func (m *Index) GetRetired() bool {
+	if m != nil {
+		return m.Retired
+	}
+	return false
+}
+
+func (m *Index) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+func (m *Index) GetMerkleDepth() int32 {
+	if m != nil {
+		return m.MerkleDepth
+	}
+	return 0
+}
+
+type Interest struct {
+	Owners      []string `protobuf:"bytes,1,rep,name=owners" json:"owners,omitempty"`
+	KeyPrefixes []string `protobuf:"bytes,2,rep,name=keyPrefixes" json:"keyPrefixes,omitempty"`
+}
+
+func (m *Interest) Reset()                    { *m = Interest{} }
+func (m *Interest) String() string            { return proto.CompactTextString(m) }
+func (*Interest) ProtoMessage()               {}
+func (*Interest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Interest) GetOwners() []string {
+	if m != nil {
+		return m.Owners
+	}
+	return nil
+}
+
+func (m *Interest) GetKeyPrefixes() []string {
+	if m != nil {
+		return m.KeyPrefixes
+	}
+	return nil
+}
+
+type MemberInterest struct {
+	Interest *Interest                  `protobuf:"bytes,1,opt,name=interest" json:"interest,omitempty"`
+	Version  *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=version" json:"version,omitempty"`
+	Via      string                     `protobuf:"bytes,3,opt,name=via" json:"via,omitempty"`
+}
+
+func (m *MemberInterest) Reset()                    { *m = MemberInterest{} }
+func (m *MemberInterest) String() string            { return proto.CompactTextString(m) }
+func (*MemberInterest) ProtoMessage()               {}
+func (*MemberInterest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *MemberInterest) GetInterest() *Interest {
+	if m != nil {
+		return m.Interest
+	}
+	return nil
+}
+
+func (m *MemberInterest) GetVersion() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *MemberInterest) GetVia() string {
+	if m != nil {
+		return m.Via
+	}
+	return ""
+}
+
+type Handover struct {
+	From string                     `protobuf:"bytes,1,opt,name=from" json:"from,omitempty"`
+	To   string                     `protobuf:"bytes,2,opt,name=to" json:"to,omitempty"`
+	Time *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=time" json:"time,omitempty"`
+	Keys []*StampedKey              `protobuf:"bytes,4,rep,name=keys" json:"keys,omitempty"`
+}
+
+func (m *Handover) Reset()                    { *m = Handover{} }
+func (m *Handover) String() string            { return proto.CompactTextString(m) }
+func (*Handover) ProtoMessage()               {}
+func (*Handover) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *Handover) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *Handover) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *Handover) GetTime() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *Handover) GetKeys() []*StampedKey {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
 type IndexMap struct {
-	Source  string            `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
-	Indexes map[string]*Index `protobuf:"bytes,2,rep,name=indexes" json:"indexes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Source    string                     `protobuf:"bytes,1,opt,name=source" json:"source,omitempty"`
+	Indexes   map[string]*Index          `protobuf:"bytes,2,rep,name=indexes" json:"indexes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Clock     *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=clock" json:"clock,omitempty"`
+	Interests map[string]*MemberInterest `protobuf:"bytes,4,rep,name=interests" json:"interests,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Scope     []*Interest                `protobuf:"bytes,5,rep,name=scope" json:"scope,omitempty"`
+	Handovers []*Handover                `protobuf:"bytes,6,rep,name=handovers" json:"handovers,omitempty"`
 }
 
 func (m *IndexMap) Reset()                    { *m = IndexMap{} }
 func (m *IndexMap) String() string            { return proto.CompactTextString(m) }
 func (*IndexMap) ProtoMessage()               {}
-func (*IndexMap) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*IndexMap) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 func (m *IndexMap) GetSource() string {
 	if m != nil {
@@ -208,6 +422,314 @@ func (m *IndexMap) GetIndexes() map[string]*Index {
 	return nil
 }
 
+func (m *IndexMap) GetClock() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Clock
+	}
+	return nil
+}
+
+func (m *IndexMap) GetInterests() map[string]*MemberInterest {
+	if m != nil {
+		return m.Interests
+	}
+	return nil
+}
+
+func (m *IndexMap) GetScope() []*Interest {
+	if m != nil {
+		return m.Scope
+	}
+	return nil
+}
+
+func (m *IndexMap) GetHandovers() []*Handover {
+	if m != nil {
+		return m.Handovers
+	}
+	return nil
+}
+
+type MerkleNode struct {
+	Id          uint32        `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Leaf        bool          `protobuf:"varint,2,opt,name=leaf" json:"leaf,omitempty"`
+	Children    [][]byte      `protobuf:"bytes,3,rep,name=children,proto3" json:"children,omitempty"`
+	StampedKeys []*StampedKey `protobuf:"bytes,4,rep,name=stampedKeys" json:"stampedKeys,omitempty"`
+}
+
+func (m *MerkleNode) Reset()                    { *m = MerkleNode{} }
+func (m *MerkleNode) String() string            { return proto.CompactTextString(m) }
+func (*MerkleNode) ProtoMessage()               {}
+func (*MerkleNode) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *MerkleNode) GetId() uint32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *MerkleNode) GetLeaf() bool {
+	if m != nil {
+		return m.Leaf
+	}
+	return false
+}
+
+func (m *MerkleNode) GetChildren() [][]byte {
+	if m != nil {
+		return m.Children
+	}
+	return nil
+}
+
+func (m *MerkleNode) GetStampedKeys() []*StampedKey {
+	if m != nil {
+		return m.StampedKeys
+	}
+	return nil
+}
+
+type MerkleNodes struct {
+	Nodes []*MerkleNode `protobuf:"bytes,1,rep,name=nodes" json:"nodes,omitempty"`
+}
+
+func (m *MerkleNodes) Reset()                    { *m = MerkleNodes{} }
+func (m *MerkleNodes) String() string            { return proto.CompactTextString(m) }
+func (*MerkleNodes) ProtoMessage()               {}
+func (*MerkleNodes) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *MerkleNodes) GetNodes() []*MerkleNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+type MerkleNodeIDs struct {
+	Ids []uint32 `protobuf:"varint,1,rep,packed,name=ids" json:"ids,omitempty"`
+}
+
+func (m *MerkleNodeIDs) Reset()                    { *m = MerkleNodeIDs{} }
+func (m *MerkleNodeIDs) String() string            { return proto.CompactTextString(m) }
+func (*MerkleNodeIDs) ProtoMessage()               {}
+func (*MerkleNodeIDs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *MerkleNodeIDs) GetIds() []uint32 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type IndexDelta struct {
+	Since       *google_protobuf.Timestamp `protobuf:"bytes,1,opt,name=since" json:"since,omitempty"`
+	Full        bool                       `protobuf:"varint,2,opt,name=full" json:"full,omitempty"`
+	StampedKeys []*StampedKey              `protobuf:"bytes,3,rep,name=stampedKeys" json:"stampedKeys,omitempty"`
+	Deleted     []string                   `protobuf:"bytes,4,rep,name=deleted" json:"deleted,omitempty"`
+}
+
+func (m *IndexDelta) Reset()                    { *m = IndexDelta{} }
+func (m *IndexDelta) String() string            { return proto.CompactTextString(m) }
+func (*IndexDelta) ProtoMessage()               {}
+func (*IndexDelta) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *IndexDelta) GetSince() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Since
+	}
+	return nil
+}
+
+func (m *IndexDelta) GetFull() bool {
+	if m != nil {
+		return m.Full
+	}
+	return false
+}
+
+func (m *IndexDelta) GetStampedKeys() []*StampedKey {
+	if m != nil {
+		return m.StampedKeys
+	}
+	return nil
+}
+
+func (m *IndexDelta) GetDeleted() []string {
+	if m != nil {
+		return m.Deleted
+	}
+	return nil
+}
+
+type IndexRequest struct {
+	RequestSource      string                                `protobuf:"bytes,1,opt,name=requestSource" json:"requestSource,omitempty"`
+	RequestDestination string                                `protobuf:"bytes,2,opt,name=requestDestination" json:"requestDestination,omitempty"`
+	MerkleNodes        map[string]*MerkleNodeIDs             `protobuf:"bytes,3,rep,name=merkleNodes" json:"merkleNodes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MerkleRoots        map[string][]byte                     `protobuf:"bytes,4,rep,name=merkleRoots" json:"merkleRoots,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Since              map[string]*google_protobuf.Timestamp `protobuf:"bytes,5,rep,name=since" json:"since,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *IndexRequest) Reset()                    { *m = IndexRequest{} }
+func (m *IndexRequest) String() string            { return proto.CompactTextString(m) }
+func (*IndexRequest) ProtoMessage()               {}
+func (*IndexRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *IndexRequest) GetRequestSource() string {
+	if m != nil {
+		return m.RequestSource
+	}
+	return ""
+}
+
+func (m *IndexRequest) GetRequestDestination() string {
+	if m != nil {
+		return m.RequestDestination
+	}
+	return ""
+}
+
+func (m *IndexRequest) GetMerkleNodes() map[string]*MerkleNodeIDs {
+	if m != nil {
+		return m.MerkleNodes
+	}
+	return nil
+}
+
+func (m *IndexRequest) GetMerkleRoots() map[string][]byte {
+	if m != nil {
+		return m.MerkleRoots
+	}
+	return nil
+}
+
+func (m *IndexRequest) GetSince() map[string]*google_protobuf.Timestamp {
+	if m != nil {
+		return m.Since
+	}
+	return nil
+}
+
+type IndexResponse struct {
+	RequestSource string                                `protobuf:"bytes,1,opt,name=requestSource" json:"requestSource,omitempty"`
+	Source        string                                `protobuf:"bytes,2,opt,name=source" json:"source,omitempty"`
+	BuildTime     map[string]*google_protobuf.Timestamp `protobuf:"bytes,3,rep,name=buildTime" json:"buildTime,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MerkleNodes   map[string]*MerkleNodes               `protobuf:"bytes,4,rep,name=merkleNodes" json:"merkleNodes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MerkleRoots   map[string][]byte                     `protobuf:"bytes,5,rep,name=merkleRoots" json:"merkleRoots,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Deltas        map[string]*IndexDelta                `protobuf:"bytes,6,rep,name=deltas" json:"deltas,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MerkleDepths  map[string]int32                      `protobuf:"bytes,7,rep,name=merkleDepths" json:"merkleDepths,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+}
+
+func (m *IndexResponse) Reset()                    { *m = IndexResponse{} }
+func (m *IndexResponse) String() string            { return proto.CompactTextString(m) }
+func (*IndexResponse) ProtoMessage()               {}
+func (*IndexResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *IndexResponse) GetRequestSource() string {
+	if m != nil {
+		return m.RequestSource
+	}
+	return ""
+}
+
+func (m *IndexResponse) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *IndexResponse) GetBuildTime() map[string]*google_protobuf.Timestamp {
+	if m != nil {
+		return m.BuildTime
+	}
+	return nil
+}
+
+func (m *IndexResponse) GetMerkleNodes() map[string]*MerkleNodes {
+	if m != nil {
+		return m.MerkleNodes
+	}
+	return nil
+}
+
+func (m *IndexResponse) GetMerkleRoots() map[string][]byte {
+	if m != nil {
+		return m.MerkleRoots
+	}
+	return nil
+}
+
+func (m *IndexResponse) GetDeltas() map[string]*IndexDelta {
+	if m != nil {
+		return m.Deltas
+	}
+	return nil
+}
+
+func (m *IndexResponse) GetMerkleDepths() map[string]int32 {
+	if m != nil {
+		return m.MerkleDepths
+	}
+	return nil
+}
+
+type DataPush struct {
+	Origin   string `protobuf:"bytes,1,opt,name=origin" json:"origin,omitempty"`
+	Sequence uint64 `protobuf:"varint,2,opt,name=sequence" json:"sequence,omitempty"`
+	Sender   string `protobuf:"bytes,3,opt,name=sender" json:"sender,omitempty"`
+	Hops     int32  `protobuf:"varint,4,opt,name=hops" json:"hops,omitempty"`
+	Items    *Items `protobuf:"bytes,5,opt,name=items" json:"items,omitempty"`
+	Leave    bool   `protobuf:"varint,6,opt,name=leave" json:"leave,omitempty"`
+}
+
+func (m *DataPush) Reset()                    { *m = DataPush{} }
+func (m *DataPush) String() string            { return proto.CompactTextString(m) }
+func (*DataPush) ProtoMessage()               {}
+func (*DataPush) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *DataPush) GetOrigin() string {
+	if m != nil {
+		return m.Origin
+	}
+	return ""
+}
+
+func (m *DataPush) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *DataPush) GetSender() string {
+	if m != nil {
+		return m.Sender
+	}
+	return ""
+}
+
+func (m *DataPush) GetHops() int32 {
+	if m != nil {
+		return m.Hops
+	}
+	return 0
+}
+
+func (m *DataPush) GetItems() *Items {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+func (m *DataPush) GetLeave() bool {
+	if m != nil {
+		return m.Leave
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*Item)(nil), "model.Item")
 	proto.RegisterType((*Items)(nil), "model.Items")
@@ -215,7 +737,18 @@ func init() {
 	proto.RegisterType((*KeyIDPairs)(nil), "model.KeyIDPairs")
 	proto.RegisterType((*StampedKey)(nil), "model.StampedKey")
 	proto.RegisterType((*Index)(nil), "model.Index")
+	proto.RegisterType((*Interest)(nil), "model.Interest")
+	proto.RegisterType((*MemberInterest)(nil), "model.MemberInterest")
+	proto.RegisterType((*Handover)(nil), "model.Handover")
 	proto.RegisterType((*IndexMap)(nil), "model.IndexMap")
+	proto.RegisterType((*MerkleNode)(nil), "model.MerkleNode")
+	proto.RegisterType((*MerkleNodes)(nil), "model.MerkleNodes")
+	proto.RegisterType((*MerkleNodeIDs)(nil), "model.MerkleNodeIDs")
+	proto.RegisterType((*IndexDelta)(nil), "model.IndexDelta")
+	proto.RegisterType((*IndexRequest)(nil), "model.IndexRequest")
+	proto.RegisterType((*IndexResponse)(nil), "model.IndexResponse")
+	proto.RegisterType((*DataPush)(nil), "model.DataPush")
+	proto.RegisterEnum("model.IndexMode", IndexMode_name, IndexMode_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -230,6 +763,9 @@ const _ = grpc.SupportPackageIsVersion4
 
 type IndexMapCollectorClient interface {
 	CollectIndexMap(ctx context.Context, in *IndexMap, opts ...grpc.CallOption) (*google_protobuf1.Empty, error)
+	CollectIndexRequest(ctx context.Context, in *IndexRequest, opts ...grpc.CallOption) (*google_protobuf1.Empty, error)
+	CollectIndexResponse(ctx context.Context, in *IndexResponse, opts ...grpc.CallOption) (*google_protobuf1.Empty, error)
+	CollectDataPush(ctx context.Context, in *DataPush, opts ...grpc.CallOption) (*google_protobuf1.Empty, error)
 }
 
 type indexMapCollectorClient struct {
@@ -249,10 +785,40 @@ func (c *indexMapCollectorClient) CollectIndexMap(ctx context.Context, in *Index
 	return out, nil
 }
 
+func (c *indexMapCollectorClient) CollectIndexRequest(ctx context.Context, in *IndexRequest, opts ...grpc.CallOption) (*google_protobuf1.Empty, error) {
+	out := new(google_protobuf1.Empty)
+	err := grpc.Invoke(ctx, "/model.IndexMapCollector/CollectIndexRequest", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexMapCollectorClient) CollectIndexResponse(ctx context.Context, in *IndexResponse, opts ...grpc.CallOption) (*google_protobuf1.Empty, error) {
+	out := new(google_protobuf1.Empty)
+	err := grpc.Invoke(ctx, "/model.IndexMapCollector/CollectIndexResponse", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexMapCollectorClient) CollectDataPush(ctx context.Context, in *DataPush, opts ...grpc.CallOption) (*google_protobuf1.Empty, error) {
+	out := new(google_protobuf1.Empty)
+	err := grpc.Invoke(ctx, "/model.IndexMapCollector/CollectDataPush", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for IndexMapCollector service
 
 type IndexMapCollectorServer interface {
 	CollectIndexMap(context.Context, *IndexMap) (*google_protobuf1.Empty, error)
+	CollectIndexRequest(context.Context, *IndexRequest) (*google_protobuf1.Empty, error)
+	CollectIndexResponse(context.Context, *IndexResponse) (*google_protobuf1.Empty, error)
+	CollectDataPush(context.Context, *DataPush) (*google_protobuf1.Empty, error)
 }
 
 func RegisterIndexMapCollectorServer(s *grpc.Server, srv IndexMapCollectorServer) {
@@ -277,6 +843,60 @@ func _IndexMapCollector_CollectIndexMap_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IndexMapCollector_CollectIndexRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexMapCollectorServer).CollectIndexRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.IndexMapCollector/CollectIndexRequest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexMapCollectorServer).CollectIndexRequest(ctx, req.(*IndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexMapCollector_CollectIndexResponse_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IndexResponse)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexMapCollectorServer).CollectIndexResponse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.IndexMapCollector/CollectIndexResponse",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexMapCollectorServer).CollectIndexResponse(ctx, req.(*IndexResponse))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexMapCollector_CollectDataPush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DataPush)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexMapCollectorServer).CollectDataPush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/model.IndexMapCollector/CollectDataPush",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexMapCollectorServer).CollectDataPush(ctx, req.(*DataPush))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _IndexMapCollector_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "model.IndexMapCollector",
 	HandlerType: (*IndexMapCollectorServer)(nil),
@@ -285,6 +905,18 @@ var _IndexMapCollector_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CollectIndexMap",
 			Handler:    _IndexMapCollector_CollectIndexMap_Handler,
 		},
+		{
+			MethodName: "CollectIndexRequest",
+			Handler:    _IndexMapCollector_CollectIndexRequest_Handler,
+		},
+		{
+			MethodName: "CollectIndexResponse",
+			Handler:    _IndexMapCollector_CollectIndexResponse_Handler,
+		},
+		{
+			MethodName: "CollectDataPush",
+			Handler:    _IndexMapCollector_CollectDataPush_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "item.proto",
@@ -357,33 +989,88 @@ var _DataRequest_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("item.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 440 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x92, 0xc1, 0x6b, 0xd4, 0x40,
-	0x14, 0xc6, 0x99, 0xec, 0xa6, 0x75, 0xdf, 0x06, 0xdb, 0x1d, 0xa4, 0x84, 0x28, 0xb8, 0xce, 0x69,
-	0x29, 0x98, 0x4a, 0x44, 0xa9, 0x3d, 0x78, 0xb1, 0x45, 0x97, 0x22, 0xca, 0xe8, 0xc1, 0xeb, 0x6c,
-	0xf3, 0x2c, 0xa1, 0xc9, 0x4e, 0xcc, 0x4c, 0xb4, 0x01, 0xff, 0x1c, 0xff, 0x50, 0x99, 0xc9, 0x24,
-	0x9b, 0xae, 0x0b, 0xd2, 0xdb, 0xbc, 0x79, 0xbf, 0x7c, 0xef, 0xfb, 0x5e, 0x06, 0x20, 0xd3, 0x58,
-	0xc4, 0x65, 0x25, 0xb5, 0xa4, 0x7e, 0x21, 0x53, 0xcc, 0xa3, 0xa7, 0xd7, 0x52, 0x5e, 0xe7, 0x78,
-	0x62, 0x2f, 0x57, 0xf5, 0xf7, 0x13, 0x9d, 0x15, 0xa8, 0xb4, 0x28, 0xca, 0x96, 0x8b, 0x1e, 0x6f,
-	0x03, 0x58, 0x94, 0xba, 0x69, 0x9b, 0xec, 0x37, 0x8c, 0x97, 0x1a, 0x0b, 0x4a, 0x61, 0x9c, 0x0a,
-	0x2d, 0x42, 0x32, 0x27, 0x8b, 0x80, 0xdb, 0x33, 0x3d, 0x84, 0xd1, 0x0d, 0x36, 0xa1, 0x37, 0x27,
-	0x8b, 0x09, 0x37, 0x47, 0x7a, 0x0a, 0x93, 0x5e, 0x3d, 0x1c, 0xcd, 0xc9, 0x62, 0x9a, 0x44, 0x71,
-	0x2b, 0x1f, 0x77, 0xf2, 0xf1, 0xd7, 0x8e, 0xe0, 0x1b, 0x98, 0x3e, 0x02, 0x5f, 0xfe, 0x5a, 0x63,
-	0x15, 0x8e, 0xad, 0x5a, 0x5b, 0xb0, 0x63, 0xf0, 0xcd, 0x74, 0x45, 0x9f, 0x81, 0x6f, 0x92, 0xa9,
-	0x90, 0xcc, 0x47, 0x8b, 0x69, 0x32, 0x8d, 0x6d, 0xb6, 0xd8, 0x34, 0x79, 0xdb, 0x61, 0xcf, 0x61,
-	0x72, 0x89, 0xcd, 0xf2, 0xfc, 0xb3, 0xc8, 0xaa, 0xce, 0x1a, 0xd9, 0x58, 0x7b, 0x08, 0x5e, 0x96,
-	0x3a, 0xaf, 0x5e, 0x96, 0xb2, 0xb7, 0x00, 0x3d, 0xae, 0xe8, 0x0b, 0x80, 0x9b, 0xbe, 0x72, 0x43,
-	0x0e, 0xdd, 0x90, 0x1e, 0xe3, 0x03, 0x86, 0x7d, 0x03, 0xf8, 0x62, 0x9c, 0x63, 0x7a, 0x89, 0xcd,
-	0x8e, 0x79, 0x77, 0x56, 0xe1, 0xdd, 0x63, 0x15, 0xec, 0x16, 0xfc, 0xe5, 0x3a, 0xc5, 0x5b, 0x23,
-	0xb1, 0xaa, 0xb3, 0x3c, 0x35, 0x94, 0x95, 0xfe, 0x8f, 0x44, 0x0f, 0xd3, 0x57, 0x10, 0xd8, 0x3b,
-	0x6d, 0xdd, 0xa9, 0xd0, 0xb3, 0x81, 0x66, 0x2e, 0xd0, 0xc6, 0x37, 0xbf, 0x83, 0xb1, 0x3f, 0x04,
-	0x1e, 0xd8, 0xd1, 0x1f, 0x45, 0x49, 0x8f, 0x60, 0x4f, 0xc9, 0xba, 0xba, 0x42, 0x97, 0xca, 0x55,
-	0xf4, 0x35, 0xec, 0x67, 0x86, 0xc1, 0x4e, 0xf6, 0x49, 0xf7, 0x33, 0xdc, 0x97, 0xed, 0x01, 0xd5,
-	0xc5, 0x5a, 0x57, 0x0d, 0xef, 0xe0, 0xe8, 0x03, 0x04, 0xc3, 0xc6, 0x8e, 0x95, 0x31, 0xf0, 0x7f,
-	0x8a, 0xbc, 0x46, 0xb7, 0xae, 0x60, 0xa8, 0xcb, 0xdb, 0xd6, 0x99, 0x77, 0x4a, 0x92, 0x4f, 0x30,
-	0xeb, 0x66, 0xbd, 0x93, 0x79, 0x8e, 0x57, 0x5a, 0x56, 0xf4, 0x0c, 0x0e, 0x5c, 0xd1, 0x27, 0x38,
-	0xd8, 0x32, 0x16, 0x1d, 0xfd, 0xb3, 0xbd, 0x0b, 0xf3, 0xd4, 0x93, 0x37, 0x30, 0x3d, 0x17, 0x5a,
-	0x70, 0xfc, 0x51, 0xa3, 0xd2, 0xf4, 0x18, 0xf6, 0xdf, 0xa3, 0x36, 0x37, 0x74, 0xb6, 0xfd, 0x06,
-	0x54, 0x14, 0x0c, 0xde, 0x9e, 0x5a, 0xed, 0x59, 0xa9, 0x97, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff,
-	0x9e, 0xc4, 0xf6, 0xad, 0x79, 0x03, 0x00, 0x00,
+	// 1315 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0x5f, 0x6f, 0x1b, 0x45,
+	0x10, 0xe7, 0x6c, 0x9f, 0x63, 0x8f, 0x9d, 0x34, 0xd9, 0x86, 0xea, 0x64, 0x50, 0x71, 0x8f, 0x96,
+	0x46, 0x29, 0x75, 0xab, 0xb4, 0x54, 0xa5, 0x42, 0xa0, 0x16, 0xa7, 0x6d, 0x68, 0x82, 0xaa, 0x4d,
+	0x90, 0x78, 0xbd, 0xf8, 0x26, 0xc9, 0x29, 0xe7, 0x3b, 0x73, 0x7b, 0x0e, 0xc9, 0x23, 0x0f, 0xbc,
+	0x21, 0x1e, 0x90, 0xe0, 0x1d, 0x09, 0xbe, 0x00, 0x5f, 0x80, 0xaf, 0x86, 0xf6, 0xdf, 0xdd, 0x6e,
+	0x72, 0x4e, 0x8c, 0xd4, 0xb7, 0xfd, 0xf3, 0x9b, 0xdf, 0xce, 0xce, 0xfc, 0x66, 0x6e, 0x0f, 0x20,
+	0xca, 0x71, 0x3c, 0x98, 0x64, 0x69, 0x9e, 0x12, 0x77, 0x9c, 0x86, 0x18, 0xf7, 0x3e, 0x3a, 0x4c,
+	0xd3, 0xc3, 0x18, 0x1f, 0x88, 0xc5, 0xfd, 0xe9, 0xc1, 0x83, 0x3c, 0x1a, 0x23, 0xcb, 0x83, 0xf1,
+	0x44, 0xe2, 0x7a, 0x1f, 0x9c, 0x07, 0xe0, 0x78, 0x92, 0x9f, 0xc9, 0x4d, 0xff, 0x1f, 0x07, 0x1a,
+	0x5b, 0x39, 0x8e, 0x09, 0x81, 0x46, 0x18, 0xe4, 0x81, 0xe7, 0xf4, 0x9d, 0xb5, 0x2e, 0x15, 0x63,
+	0xb2, 0x0c, 0xf5, 0x63, 0x3c, 0xf3, 0x6a, 0x7d, 0x67, 0xad, 0x4d, 0xf9, 0x90, 0x3c, 0x85, 0x76,
+	0x41, 0xef, 0xd5, 0xfb, 0xce, 0x5a, 0x67, 0xa3, 0x37, 0x90, 0xfc, 0x03, 0xcd, 0x3f, 0xd8, 0xd3,
+	0x08, 0x5a, 0x82, 0xc9, 0x2a, 0xb8, 0xe9, 0x8f, 0x09, 0x66, 0x5e, 0x43, 0xb0, 0xc9, 0x09, 0x79,
+	0x0c, 0x0b, 0x78, 0x3a, 0x89, 0x32, 0x64, 0x9e, 0x7b, 0x25, 0x9b, 0x86, 0xfa, 0xeb, 0xe0, 0x72,
+	0x9f, 0x19, 0xb9, 0x05, 0x2e, 0x0f, 0x08, 0xf3, 0x9c, 0x7e, 0x7d, 0xad, 0xb3, 0xd1, 0x19, 0x88,
+	0x90, 0x0c, 0xf8, 0x26, 0x95, 0x3b, 0xfe, 0x7d, 0x68, 0xbf, 0xc1, 0xb3, 0xad, 0xe1, 0xdb, 0x20,
+	0xca, 0xf4, 0x85, 0x9c, 0xf2, 0x42, 0x4b, 0x50, 0x8b, 0x42, 0x75, 0xc3, 0x5a, 0x14, 0xfa, 0xbf,
+	0x38, 0x00, 0x05, 0x9e, 0x91, 0x87, 0x00, 0xc7, 0xc5, 0x4c, 0x9d, 0xb2, 0xac, 0x4e, 0x29, 0x60,
+	0xd4, 0xc0, 0x90, 0x1b, 0xd0, 0x64, 0xe9, 0x34, 0x1b, 0xa1, 0x22, 0x55, 0x33, 0xd2, 0x87, 0x4e,
+	0x88, 0x2c, 0x8f, 0x92, 0x20, 0x8f, 0xd2, 0x44, 0xc4, 0xae, 0x4d, 0xcd, 0x25, 0x9e, 0x81, 0xa3,
+	0x74, 0xc2, 0x44, 0x80, 0x5c, 0x2a, 0xc6, 0xfe, 0xaf, 0x0e, 0xc0, 0x2e, 0xbf, 0x3c, 0x86, 0x6f,
+	0xf0, 0xac, 0xc2, 0x7f, 0x2b, 0x21, 0xb5, 0xff, 0x93, 0x10, 0x23, 0xf4, 0xf5, 0xf9, 0x43, 0xff,
+	0x5b, 0x0d, 0xdc, 0xad, 0x24, 0xc4, 0x53, 0x7e, 0xf2, 0xfe, 0x34, 0x8a, 0x43, 0x0e, 0xf2, 0x9c,
+	0x2b, 0x19, 0x4a, 0x30, 0xf9, 0x0c, 0xba, 0x62, 0x2d, 0x17, 0x97, 0x62, 0x5e, 0x4d, 0x84, 0x75,
+	0x45, 0x85, 0xb5, 0xbc, 0x2e, 0xb5, 0x60, 0xe4, 0x36, 0x34, 0x38, 0x42, 0x78, 0xbb, 0x54, 0x64,
+	0x41, 0x38, 0xb3, 0x93, 0x86, 0x48, 0xc5, 0x2e, 0xb9, 0x09, 0x30, 0xc6, 0xec, 0x38, 0x46, 0x9a,
+	0xa6, 0xb9, 0x88, 0x65, 0x97, 0x1a, 0x2b, 0xc4, 0x83, 0x85, 0x0c, 0xf3, 0x28, 0xc3, 0x50, 0x28,
+	0xae, 0x45, 0xf5, 0x94, 0x67, 0x2e, 0x8c, 0x0e, 0x91, 0xe5, 0x5e, 0x53, 0x58, 0xa9, 0x19, 0xcf,
+	0x9c, 0xb4, 0x1f, 0xe2, 0x24, 0x3f, 0xf2, 0x16, 0x44, 0x7a, 0xcc, 0x25, 0x7f, 0x08, 0xad, 0xad,
+	0x24, 0xc7, 0x8c, 0xa3, 0x6f, 0x40, 0x53, 0x48, 0x5b, 0xaa, 0xa5, 0x4d, 0xd5, 0x8c, 0xb3, 0x1c,
+	0xe3, 0xd9, 0xdb, 0x0c, 0x0f, 0xa2, 0x53, 0x94, 0x77, 0x6e, 0x53, 0x73, 0xc9, 0xff, 0xd9, 0x81,
+	0xa5, 0x1d, 0x1c, 0xef, 0x63, 0x56, 0x90, 0xdd, 0x83, 0x56, 0xa4, 0xc6, 0x2a, 0xc4, 0xd7, 0x8a,
+	0x6b, 0xcb, 0x65, 0x5a, 0x00, 0x78, 0x42, 0x4f, 0x30, 0x63, 0x5c, 0x5d, 0x57, 0x0b, 0x41, 0x43,
+	0xb9, 0xa4, 0x4e, 0xa2, 0x40, 0xe9, 0x91, 0x0f, 0xb9, 0x1f, 0xad, 0xd7, 0x41, 0x12, 0xa6, 0x27,
+	0x98, 0x71, 0x51, 0x1e, 0x64, 0xe9, 0x58, 0x49, 0x4e, 0x8c, 0x79, 0xcd, 0xe4, 0xa9, 0xae, 0x99,
+	0x3c, 0x25, 0x03, 0x68, 0x70, 0x59, 0xcd, 0x21, 0x23, 0x81, 0x23, 0x77, 0xa0, 0x71, 0xcc, 0xf3,
+	0xde, 0x98, 0x95, 0x77, 0xb1, 0xed, 0xff, 0x5b, 0x87, 0x96, 0xcc, 0x6e, 0x30, 0x31, 0xca, 0xca,
+	0xb1, 0xca, 0xea, 0x09, 0x2c, 0x44, 0x1c, 0x83, 0x5a, 0x46, 0x1f, 0x5a, 0xba, 0x08, 0x26, 0x72,
+	0x80, 0x6c, 0x33, 0xc9, 0xb3, 0x33, 0xaa, 0xc1, 0xe4, 0x21, 0xb8, 0xa3, 0x38, 0x1d, 0x1d, 0xcf,
+	0xe1, 0xb4, 0x04, 0x92, 0x2f, 0xa0, 0xad, 0x43, 0xad, 0x5d, 0xbf, 0x79, 0xf1, 0x2c, 0x05, 0x90,
+	0xa7, 0x95, 0x06, 0xe4, 0x0e, 0xb8, 0x6c, 0x94, 0x4e, 0xd0, 0x73, 0xfb, 0xf5, 0xaa, 0x34, 0xca,
+	0x5d, 0x72, 0x1f, 0xda, 0x47, 0x2a, 0xf4, 0xcc, 0x6b, 0x5a, 0x50, 0x9d, 0x12, 0x5a, 0x22, 0x7a,
+	0xaf, 0xa1, 0x6b, 0x5e, 0xaf, 0xa2, 0x3f, 0xf8, 0xe0, 0x9e, 0x04, 0xf1, 0x14, 0x95, 0x24, 0xba,
+	0xa6, 0xc7, 0x54, 0x6e, 0x3d, 0xab, 0x3d, 0x75, 0x7a, 0xbb, 0xb0, 0x64, 0x3b, 0x5f, 0xc1, 0x75,
+	0xcf, 0xe6, 0x7a, 0x5f, 0x71, 0xd9, 0x9a, 0x35, 0x48, 0xfd, 0x9f, 0x1c, 0x80, 0x1d, 0x51, 0x27,
+	0xdf, 0xf2, 0xd2, 0x94, 0xbd, 0x96, 0x13, 0x2e, 0xf2, 0x5e, 0xcb, 0xb5, 0x15, 0x63, 0x70, 0x20,
+	0xe8, 0x5a, 0x54, 0x8c, 0x49, 0x0f, 0x5a, 0xa3, 0xa3, 0x28, 0x0e, 0x33, 0xe4, 0x3d, 0xb2, 0xbe,
+	0xd6, 0xa5, 0xc5, 0x9c, 0x3c, 0x82, 0x0e, 0x2b, 0x44, 0x72, 0x89, 0x7c, 0x4c, 0x94, 0xff, 0x04,
+	0x3a, 0xa5, 0x0b, 0x8c, 0xdc, 0x05, 0x37, 0xe1, 0x03, 0xcf, 0xb1, 0xac, 0x4b, 0x08, 0x95, 0xfb,
+	0xfe, 0x2d, 0x58, 0x2c, 0x17, 0xb7, 0x86, 0x8c, 0xc7, 0x23, 0x0a, 0xa5, 0xdd, 0x22, 0xe5, 0x43,
+	0xff, 0x4f, 0x07, 0x40, 0x04, 0x72, 0x88, 0x71, 0x1e, 0x70, 0x49, 0xb1, 0x28, 0x19, 0xcd, 0xd3,
+	0x0c, 0x25, 0x50, 0x14, 0xd7, 0x34, 0x8e, 0x75, 0x00, 0xf8, 0xf8, 0xfc, 0x25, 0xeb, 0xf3, 0x5c,
+	0x92, 0x37, 0xb5, 0x10, 0x63, 0xcc, 0x31, 0x14, 0x51, 0x69, 0x53, 0x3d, 0xf5, 0xff, 0x68, 0x28,
+	0x89, 0x50, 0xfc, 0x61, 0xca, 0xbb, 0xc4, 0x6d, 0x58, 0xcc, 0xe4, 0x70, 0xd7, 0xac, 0x27, 0x7b,
+	0x91, 0x0c, 0x80, 0xa8, 0x85, 0xa1, 0xf1, 0xd1, 0x92, 0x25, 0x5f, 0xb1, 0x43, 0x5e, 0xea, 0x1e,
+	0x29, 0xa2, 0xac, 0xbc, 0xbe, 0x6d, 0x89, 0x4d, 0x1a, 0x19, 0x91, 0x56, 0x45, 0x62, 0x1a, 0x96,
+	0x3c, 0xbc, 0x57, 0xeb, 0x14, 0x5f, 0xc2, 0x23, 0x60, 0x16, 0x8f, 0x58, 0x21, 0x8f, 0x75, 0x2e,
+	0xdc, 0x8b, 0x85, 0xaa, 0x19, 0x76, 0x39, 0x40, 0xda, 0x4a, 0x70, 0x6f, 0x0f, 0x96, 0xcf, 0xbb,
+	0x57, 0x51, 0x06, 0xeb, 0x76, 0x19, 0xac, 0x5e, 0x90, 0xd0, 0xd6, 0x90, 0x99, 0xa5, 0xf5, 0xa5,
+	0x66, 0x2d, 0x9d, 0xad, 0x60, 0x5d, 0x35, 0x59, 0xbb, 0xa6, 0xfd, 0x1e, 0x40, 0xe9, 0x6a, 0x85,
+	0xe5, 0x43, 0xdb, 0x9f, 0x4b, 0x75, 0x57, 0xd6, 0xe6, 0xdf, 0x4d, 0x58, 0x54, 0xe1, 0x60, 0x93,
+	0x34, 0x61, 0x38, 0xa7, 0x32, 0x66, 0xbd, 0x6f, 0x9e, 0x9b, 0xcf, 0x01, 0x99, 0xff, 0x8f, 0xed,
+	0xa8, 0xcb, 0x63, 0x06, 0x2f, 0x34, 0x4a, 0xf5, 0xc8, 0xc2, 0x8a, 0xbc, 0xb2, 0x45, 0x24, 0x93,
+	0x7f, 0xa7, 0x92, 0xe4, 0x72, 0x15, 0xbd, 0xb2, 0x55, 0xe4, 0x5e, 0x49, 0x34, 0x4b, 0x46, 0x4f,
+	0xa1, 0x19, 0xf2, 0xda, 0xd6, 0xbd, 0xb8, 0x5f, 0xc9, 0x21, 0xca, 0x5f, 0x99, 0x2b, 0x3c, 0xf9,
+	0x06, 0xba, 0xc6, 0x0b, 0x81, 0x79, 0x0b, 0xc2, 0xfe, 0x93, 0x4b, 0x7c, 0x90, 0x40, 0xc9, 0x62,
+	0xd9, 0xf6, 0xbe, 0x87, 0x25, 0x3b, 0x68, 0xef, 0x4a, 0x04, 0x3d, 0x3a, 0x97, 0xe0, 0xd7, 0x6c,
+	0x6e, 0x72, 0x41, 0xf0, 0xef, 0x54, 0xee, 0xdb, 0xd0, 0x31, 0x02, 0x5a, 0x61, 0x7a, 0xd7, 0x76,
+	0x67, 0xc5, 0x8c, 0xa9, 0xb0, 0x34, 0xd9, 0xbe, 0x82, 0x95, 0x0b, 0xe1, 0xbd, 0xca, 0x1d, 0xd7,
+	0xac, 0x93, 0xbf, 0x1c, 0x68, 0x0d, 0x83, 0x3c, 0x78, 0x3b, 0x65, 0x47, 0xe2, 0x71, 0x97, 0x45,
+	0x87, 0x51, 0xa2, 0x5f, 0x21, 0x72, 0xc6, 0xbf, 0x5a, 0x8c, 0x57, 0x49, 0xa2, 0xca, 0xa2, 0x41,
+	0x8b, 0xb9, 0x28, 0x18, 0x4c, 0x42, 0xcc, 0xd4, 0x1b, 0x4b, 0xcd, 0xaa, 0x9e, 0xfb, 0xfc, 0x6b,
+	0x2d, 0xff, 0x67, 0x5c, 0xfb, 0x6b, 0xcd, 0xd7, 0xd4, 0x0f, 0x0d, 0x77, 0x35, 0xc6, 0xe0, 0x04,
+	0xc5, 0x2b, 0xb5, 0x45, 0xe5, 0x64, 0xfd, 0x53, 0x68, 0x17, 0x2f, 0x61, 0xd2, 0x82, 0xc6, 0xcb,
+	0xef, 0xb6, 0xb7, 0x97, 0xdf, 0x23, 0x00, 0xcd, 0x9d, 0x4d, 0xfa, 0x66, 0x7b, 0x73, 0xd9, 0x21,
+	0x6d, 0x70, 0x87, 0x9b, 0xdb, 0x7b, 0xcf, 0x97, 0x6b, 0x1b, 0xbf, 0xd7, 0x60, 0x45, 0x3f, 0x5a,
+	0xbe, 0x4e, 0xe3, 0x18, 0x47, 0x79, 0x9a, 0x91, 0x67, 0x70, 0x4d, 0x4d, 0xf4, 0x1e, 0xb9, 0x76,
+	0xee, 0x85, 0xd3, 0xbb, 0x71, 0x41, 0x58, 0x9b, 0xfc, 0x6f, 0x92, 0xbc, 0x80, 0xeb, 0xa6, 0xad,
+	0xfe, 0xda, 0x5c, 0xaf, 0x68, 0xbc, 0x33, 0x39, 0x86, 0xb0, 0x6a, 0x73, 0xa8, 0xc6, 0xb4, 0x5a,
+	0x55, 0x35, 0x33, 0x59, 0xca, 0x5b, 0x14, 0x69, 0xd3, 0xb7, 0xd0, 0x0b, 0xb3, 0x6c, 0x37, 0x3e,
+	0x87, 0x0e, 0xc7, 0x68, 0xef, 0xd7, 0x61, 0xe1, 0x15, 0x0a, 0x1a, 0xb2, 0x72, 0xfe, 0xa7, 0x8f,
+	0xf5, 0xac, 0xec, 0xec, 0x37, 0x05, 0xd5, 0xa3, 0xff, 0x06, 0x00, 0xbb, 0xf1, 0x01, 0x10, 0xa2,
+	0x0f, 0x00, 0x00,
 }
@@ -1,53 +1,133 @@
 package grpc
 
 import (
+	"fmt"
+	"net"
+
 	"github.com/dbenque/datafan/pkg/engine"
+	"github.com/dbenque/datafan/pkg/grpc/model"
 	"github.com/dbenque/datafan/pkg/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
-const (
-	port = ":41120"
-)
+//Server is a LocalMember exposing its data to the mesh through grpc
+type Server struct {
+	id         string
+	storage    store.Store
+	codec      engine.Codec
+	connector  engine.Connector
+	clock      *engine.HybridClock
+	grpcServer *grpc.Server
+}
+
+var _ engine.LocalMember = &Server{}
+
+//NewServer creates a member backed by storage. Items are serialized with codec on the wire.
+//The options size the worker pool and the queues of the connector
+func NewServer(id string, storage store.Store, codec engine.Codec, options ...engine.ConnectorOption) *Server {
+	s := &Server{
+		id:         id,
+		storage:    storage,
+		codec:      codec,
+		clock:      engine.NewHybridClock(engine.DefaultMaxClockOffset),
+		grpcServer: grpc.NewServer(),
+	}
+	s.connector = engine.NewConnector(s, newConnectorFactory(codec), options...)
+	core := s.connector.(*engine.ConnectorImpl).ConnectorCore.(*connector)
+	model.RegisterIndexMapCollectorServer(s.grpcServer, core)
+	model.RegisterDataRequestServer(s.grpcServer, core)
+	// Register reflection service on gRPC server.
+	reflection.Register(s.grpcServer)
+	return s
+}
 
-type server struct {
-	id      string
-	storage store.Store
+//Serve accepts the connections of remote members on lis. It blocks until Stop is called
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpcServer.Serve(lis)
 }
 
-var _ engine.LocalMember = &server{}
+//Stop closes the listener and the connections to the remote members
+func (s *Server) Stop() {
+	s.grpcServer.Stop()
+	for _, m := range s.connector.(*engine.ConnectorImpl).ConnectorCore.(*connector).remotes() {
+		m.Close()
+	}
+}
 
-func (s *server) ID() engine.ID {
+//Dial prepares a RemoteMember that can be given to Engine.AddMember
+func (s *Server) Dial(id, address string) (*RemoteMember, error) {
+	return Dial(engine.ID(id), address, s.codec)
+}
+
+func (s *Server) ID() engine.ID {
 	return engine.ID(s.id)
 }
 
-func (s *server) GetIndexes() engine.IndexMap {
-	return engine.IndexMap{}
+//Clock returns the clock stamping the items written to the server
+func (s *Server) Clock() engine.Clock {
+	return s.clock
 }
-func (s *server) GetData(engine.KeyIDPairs) engine.Items {
-	return engine.Items{}
+
+//Metrics returns the depth of the queues of the connector
+func (s *Server) Metrics() engine.ConnectorMetrics {
+	return s.connector.(*engine.ConnectorImpl).Metrics()
 }
-func (s *server) Delete(kp engine.KeyIDPairs) {
+
+func (s *Server) GetStorage() store.Store {
+	return s.storage
+}
+
+func (s *Server) GetIndexes() engine.IndexMap {
+	im := engine.IndexMap{
+		Source:  s.ID(),
+		Indexes: map[engine.ID]engine.Index{},
+	}
+	for _, id := range s.storage.GetMembers() {
+		im.Indexes[id] = s.storage.GetIndex(id)
+	}
+	return im
+}
+func (s *Server) GetData(kps engine.KeyIDPairs) engine.Items {
+	items := engine.Items{}
+	for _, kp := range kps {
+		i := s.storage.Get(kp)
+		if i == nil {
+			continue
+		}
+		items = append(items, i)
+	}
+	return items
+}
+func (s *Server) Delete(kp engine.KeyIDPairs) {
 	s.storage.MultiDelete(kp)
 }
-func (s *server) Put(items engine.Items) {
+func (s *Server) Put(items engine.Items) {
 	s.storage.MultiSet(items)
 }
-func (s *server) GetConnector() engine.Connector {
+func (s *Server) PurgeOwner(id engine.ID) {
+	s.storage.DeleteShard(id)
+}
+func (s *Server) GetConnector() engine.Connector {
+	return s.connector
+}
+
+//Write stamps an item owned by the server with its clock and stores it. The engine pushes it if WithPush is set
+func (s *Server) Write(item engine.Item) error {
+	if item.OwnedBy() != s.ID() {
+		return fmt.Errorf("the write interface is reserved to owned items, %s is owned by %s", item.GetKey(), item.OwnedBy())
+	}
+	stampable, ok := item.(engine.StampableItem)
+	if !ok {
+		return fmt.Errorf("write %s: %w", item.GetKey(), engine.ErrNotStampable)
+	}
+	stamped := stampable.Stamp(s.clock.Now())
+	s.storage.Set(stamped)
+	s.connector.Written(engine.Items{stamped})
 	return nil
 }
 
-// func main() {
-
-// 	lis, err := net.Listen("tcp", port)
-// 	if err != nil {
-// 		log.Fatalf("failed to listen: %v", err)
-// 	}
-// 	s := grpc.NewServer()
-// 	model.RegisterIndexMapCollectorServer(s, &collector{})
-// 	model.RegisterDataRequestServer(s, &dataRequestor{})
-// 	// Register reflection service on gRPC server.
-// 	reflection.Register(s)
-// 	if err := s.Serve(lis); err != nil {
-// 		log.Fatalf("failed to serve: %v", err)
-// 	}
-// }
+//Remove deletes an item owned by the server
+func (s *Server) Remove(key engine.Key) {
+	s.storage.Delete(engine.KeyIDPair{Key: key, ID: s.ID()})
+}
@@ -0,0 +1,398 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dbenque/datafan/pkg/engine"
+)
+
+const (
+	opSet    byte = 1
+	opDelete byte = 2
+	opShard  byte = 3
+	opDrop   byte = 4
+
+	recordHeaderSize = 8        // length + crc32 of the payload
+	maxRecordSize    = 64 << 20 // a longer length can only come from a corrupted header
+)
+
+//MinCompactionRecords is the minimum size of the log (in records) before a compaction is considered
+var MinCompactionRecords = 1024
+
+var errCorruptedRecord = errors.New("corrupted record")
+
+//FileStore is a Store persisted in an append-only log.
+//
+//Every mutation is appended to the log before being applied to the in memory view, so the content
+//survives a crash of the process: at startup the log is replayed and a record truncated by a crash
+//is discarded. The log is compacted (rewritten with the live items only) when it holds more than
+//twice as many records as live items.
+//
+//The Store interface does not report errors: the first write error makes the FileStore read only and is
+//returned by Err. An item that can't be marshaled is skipped, logged and counted by Skipped, the store stays writable.
+type FileStore struct {
+	sync.RWMutex
+	view    *MapStore
+	codec   engine.Codec
+	path    string
+	file    *os.File
+	records int
+	err     error
+	skipped uint64
+}
+
+var _ Store = &FileStore{}
+
+//NewFileStore opens (or creates) the log at path and reloads its content. Items are serialized with codec
+func NewFileStore(path string, codec engine.Codec) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{
+		view:  NewMapStore(),
+		codec: codec,
+		path:  path,
+		file:  f,
+	}
+	if err := s.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+//replay loads the log in the view and truncates the log after the last valid record
+func (s *FileStore) replay() error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		payload, err := readRecord(r)
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF && err != errCorruptedRecord {
+				return err
+			}
+			if err != io.EOF {
+				log.Printf("FileStore %s: discarding the end of the log after offset %d: %v", s.path, offset, err)
+			}
+			break
+		}
+		if err := s.apply(payload); err != nil {
+			return fmt.Errorf("can't replay record at offset %d: %v", offset, err)
+		}
+		offset += int64(recordHeaderSize + len(payload))
+		s.records++
+	}
+	if err := s.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+func (s *FileStore) apply(payload []byte) error {
+	if len(payload) == 0 {
+		return errCorruptedRecord
+	}
+	switch payload[0] {
+	case opSet:
+		i, err := s.codec.Unmarshal(payload[1:])
+		if err != nil {
+			return err
+		}
+		s.view.set(i)
+	case opDelete:
+		kp, err := decodeKeyIDPair(payload[1:])
+		if err != nil {
+			return err
+		}
+		if shard, ok := s.view.internal[kp.ID]; ok {
+			delete(shard, kp.Key)
+		}
+	case opShard:
+		id := engine.ID(payload[1:])
+		if _, ok := s.view.internal[id]; !ok {
+			s.view.internal[id] = map[engine.Key]engine.Item{}
+		}
+	case opDrop:
+		delete(s.view.internal, engine.ID(payload[1:]))
+	default:
+		return fmt.Errorf("unknown operation %d", payload[0])
+	}
+	return nil
+}
+
+func readRecord(r io.Reader) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return nil, errCorruptedRecord
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errCorruptedRecord
+	}
+	return payload, nil
+}
+
+func appendRecord(buf *bytes.Buffer, payload []byte) {
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload))
+	buf.Write(header)
+	buf.Write(payload)
+}
+
+func (s *FileStore) setPayload(i engine.Item) ([]byte, error) {
+	data, err := s.codec.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= maxRecordSize {
+		return nil, fmt.Errorf("the item takes %d bytes, the limit is %d", len(data), maxRecordSize)
+	}
+	return append([]byte{opSet}, data...), nil
+}
+
+func deletePayload(kp engine.KeyIDPair) []byte {
+	payload := []byte{opDelete}
+	payload = binary.AppendUvarint(payload, uint64(len(kp.ID)))
+	payload = append(payload, kp.ID...)
+	return append(payload, kp.Key...)
+}
+
+func decodeKeyIDPair(data []byte) (engine.KeyIDPair, error) {
+	l, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < l {
+		return engine.KeyIDPair{}, errCorruptedRecord
+	}
+	return engine.KeyIDPair{ID: engine.ID(data[n : n+int(l)]), Key: engine.Key(data[n+int(l):])}, nil
+}
+
+func shardPayload(id engine.ID) []byte {
+	return append([]byte{opShard}, id...)
+}
+
+func dropPayload(id engine.ID) []byte {
+	return append([]byte{opDrop}, id...)
+}
+
+//write appends the records to the log. Caller must hold the write lock
+func (s *FileStore) write(buf *bytes.Buffer, count int) bool {
+	if s.err != nil {
+		return false
+	}
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		s.fail(err)
+		return false
+	}
+	s.records += count
+	return true
+}
+
+func (s *FileStore) fail(err error) {
+	log.Printf("FileStore %s is now read only: %v", s.path, err)
+	s.err = err
+}
+
+//Err returns the error that made the store read only, if any
+func (s *FileStore) Err() error {
+	s.RLock()
+	defer s.RUnlock()
+	return s.err
+}
+
+//Skipped returns the number of items not stored because they could not be marshaled
+func (s *FileStore) Skipped() uint64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.skipped
+}
+
+func (s *FileStore) GetMembers() []engine.ID {
+	return s.view.GetMembers()
+}
+func (s *FileStore) GetIndex(id engine.ID) engine.Index {
+	return s.view.GetIndex(id)
+}
+func (s *FileStore) Get(kp engine.KeyIDPair) engine.Item {
+	return s.view.Get(kp)
+}
+
+func (s *FileStore) Set(i engine.Item) {
+	s.MultiSet(engine.Items{i})
+}
+func (s *FileStore) MultiSet(items engine.Items) {
+	s.Lock()
+	defer s.Unlock()
+	var buf bytes.Buffer
+	written := make(engine.Items, 0, len(items))
+	for _, i := range items {
+		payload, err := s.setPayload(i)
+		if err != nil {
+			// only that item is lost, the other items of the batch are stored
+			log.Printf("FileStore %s: skipping %s/%s: %v", s.path, i.OwnedBy(), i.GetKey(), err)
+			s.skipped++
+			continue
+		}
+		appendRecord(&buf, payload)
+		written = append(written, i)
+	}
+	if len(written) > 0 && s.write(&buf, len(written)) {
+		s.view.MultiSet(written)
+		s.compactIfNeeded()
+	}
+}
+
+func (s *FileStore) Delete(kp engine.KeyIDPair) {
+	s.MultiDelete(engine.KeyIDPairs{kp})
+}
+func (s *FileStore) MultiDelete(kps engine.KeyIDPairs) {
+	s.Lock()
+	defer s.Unlock()
+	var buf bytes.Buffer
+	for _, kp := range kps {
+		appendRecord(&buf, deletePayload(kp))
+	}
+	if s.write(&buf, len(kps)) {
+		s.view.MultiDelete(kps)
+		s.compactIfNeeded()
+	}
+}
+
+//DeleteShard removes the owner id and all its items
+func (s *FileStore) DeleteShard(id engine.ID) {
+	s.Lock()
+	defer s.Unlock()
+	var buf bytes.Buffer
+	appendRecord(&buf, dropPayload(id))
+	if s.write(&buf, 1) {
+		s.view.DeleteShard(id)
+		s.compactIfNeeded()
+	}
+}
+
+//Count returns the number of items in the store, all owners included
+func (s *FileStore) Count() int {
+	return s.view.Count()
+}
+
+func (s *FileStore) compactIfNeeded() {
+	live := s.view.Count() + len(s.view.internal)
+	if s.records < MinCompactionRecords || s.records < 2*live {
+		return
+	}
+	if err := s.compact(); err != nil {
+		s.fail(err)
+	}
+}
+
+//Compact rewrites the log with the live items only
+func (s *FileStore) Compact() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.compact(); err != nil {
+		s.fail(err)
+		return err
+	}
+	return nil
+}
+
+//compact writes a snapshot of the view next to the log and atomically replaces the log with it. Caller must hold the write lock
+func (s *FileStore) compact() error {
+	tmpPath := s.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	w := bufio.NewWriter(tmp)
+	records := 0
+	var buf bytes.Buffer
+	for id, shard := range s.view.internal {
+		appendRecord(&buf, shardPayload(id))
+		records++
+		for _, i := range shard {
+			payload, err := s.setPayload(i)
+			if err != nil {
+				tmp.Close()
+				return err
+			}
+			appendRecord(&buf, payload)
+			records++
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			tmp.Close()
+			return err
+		}
+		buf.Reset()
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		tmp.Close()
+		return err
+	}
+	syncDir(filepath.Dir(s.path))
+	s.file.Close()
+	s.file = tmp
+	s.records = records
+	return nil
+}
+
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+//Sync flushes the log to the disk
+func (s *FileStore) Sync() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.file.Sync()
+}
+
+//Close flushes and closes the log. The store must not be used afterwards
+func (s *FileStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,227 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dbenque/datafan/pkg/engine"
+	"github.com/dbenque/datafan/pkg/store"
+	"github.com/dbenque/datafan/pkg/store/storetest"
+)
+
+func openFileStore(t *testing.T, path string) *store.FileStore {
+	t.Helper()
+	s, err := store.NewFileStore(path, storetest.Codec{})
+	if err != nil {
+		t.Fatalf("can't open file store: %v", err)
+	}
+	return s
+}
+
+func TestFileStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s := openFileStore(t, filepath.Join(t.TempDir(), "store.log"))
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+func TestFileStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s := openFileStore(t, path)
+	s.MultiSet(engine.Items{storetest.NewItem("M0", "a", "a"), storetest.NewItem("M0", "b", "b"), storetest.NewItem("M1", "c", "c")})
+	s.Set(storetest.NewItem("M0", "a", "a2"))
+	s.Delete(engine.KeyIDPair{ID: "M0", Key: "b"})
+	s.Delete(engine.KeyIDPair{ID: "M1", Key: "c"})
+	s.Set(storetest.NewItem("M2", "d", "d"))
+	s.DeleteShard("M2")
+	before := dump(t, s)
+	// no Close: simulate a crash of the process
+	reloaded := openFileStore(t, path)
+	defer reloaded.Close()
+	if after := dump(t, reloaded); after != before {
+		t.Fatalf("reloaded content differs:\n%s\n%s", before, after)
+	}
+	if fmt.Sprint(reloaded.GetIndex("M1").StampedKeys) != "[]" {
+		t.Fatalf("the empty shard of M1 was not reloaded")
+	}
+	for _, id := range reloaded.GetMembers() {
+		if id == "M2" {
+			t.Fatalf("the deleted shard of M2 was reloaded")
+		}
+	}
+}
+
+func TestFileStoreTruncatedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s := openFileStore(t, path)
+	s.Set(storetest.NewItem("M0", "a", "a"))
+	s.Set(storetest.NewItem("M0", "b", "b"))
+	s.Close()
+
+	// a crash in the middle of the last append leaves a partial record
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openFileStore(t, path)
+	if s.Get(engine.KeyIDPair{ID: "M0", Key: "a"}) == nil {
+		t.Fatalf("item before the partial record was lost")
+	}
+	if s.Get(engine.KeyIDPair{ID: "M0", Key: "b"}) != nil {
+		t.Fatalf("partial record was loaded")
+	}
+	// the log is usable after recovery
+	s.Set(storetest.NewItem("M0", "c", "c"))
+	s.Close()
+	s = openFileStore(t, path)
+	defer s.Close()
+	if s.Count() != 2 {
+		t.Fatalf("expected 2 items after recovery, got %d", s.Count())
+	}
+}
+
+func TestFileStoreCorruptedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s := openFileStore(t, path)
+	s.Set(storetest.NewItem("M0", "a", "a"))
+	s.Close()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0, 0, 0, 4, 1, 2, 3, 4, 'j', 'u', 'n', 'k'})
+	f.Close()
+
+	s = openFileStore(t, path)
+	defer s.Close()
+	if s.Count() != 1 {
+		t.Fatalf("expected 1 item, got %d", s.Count())
+	}
+}
+
+func TestFileStoreCorruptedLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s := openFileStore(t, path)
+	s.Set(storetest.NewItem("M0", "a", "a"))
+	s.Close()
+
+	// the length of the header is not allocated before the record is checked
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0xff, 0xff, 0xff, 0xf0, 1, 2, 3, 4, 'j', 'u', 'n', 'k'})
+	f.Close()
+
+	s = openFileStore(t, path)
+	defer s.Close()
+	if s.Count() != 1 {
+		t.Fatalf("expected 1 item, got %d", s.Count())
+	}
+	s.Set(storetest.NewItem("M0", "b", "b"))
+	if s.Err() != nil || s.Count() != 2 {
+		t.Fatalf("the log is not usable after the corrupted record was discarded: %v", s.Err())
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s := openFileStore(t, path)
+	for n := 0; n < 3*store.MinCompactionRecords; n++ {
+		s.Set(storetest.NewItem("M0", engine.Key(fmt.Sprintf("k%d", n%10)), fmt.Sprintf("v%d", n)))
+	}
+	s.Delete(engine.KeyIDPair{ID: "M0", Key: "k0"})
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := dump(t, s)
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	compacted, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compacted.Size() >= info.Size() {
+		t.Fatalf("log not compacted: %d bytes before, %d after", info.Size(), compacted.Size())
+	}
+	s.Set(storetest.NewItem("M1", "x", "x"))
+	s.Close()
+
+	s = openFileStore(t, path)
+	defer s.Close()
+	s.Delete(engine.KeyIDPair{ID: "M1", Key: "x"})
+	if after := dump(t, s); after != before {
+		t.Fatalf("content changed by compaction:\n%s\n%s", before, after)
+	}
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+}
+
+//failingCodec can't marshal the items with the key "bad"
+type failingCodec struct {
+	storetest.Codec
+}
+
+func (c failingCodec) Marshal(i engine.Item) ([]byte, error) {
+	if i.GetKey() == "bad" {
+		return nil, errors.New("can't marshal")
+	}
+	return c.Codec.Marshal(i)
+}
+
+func TestFileStoreMarshalError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s, err := store.NewFileStore(path, failingCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MultiSet(engine.Items{storetest.NewItem("M0", "a", "a"), storetest.NewItem("M0", "bad", "b"), storetest.NewItem("M0", "c", "c")})
+	if s.Skipped() != 1 || s.Err() != nil {
+		t.Fatalf("expected the item skipped and the store writable, got %d skipped and %v", s.Skipped(), s.Err())
+	}
+	if s.Count() != 2 || s.Get(engine.KeyIDPair{ID: "M0", Key: "bad"}) != nil {
+		t.Fatalf("expected the 2 other items of the batch, got %d items", s.Count())
+	}
+	// still writable afterwards
+	s.Set(storetest.NewItem("M0", "d", "d"))
+	if s.Count() != 3 {
+		t.Fatalf("the store rejected a write after the marshal error")
+	}
+	s.Close()
+
+	s = openFileStore(t, path)
+	defer s.Close()
+	if s.Count() != 3 {
+		t.Fatalf("expected 3 items after reload, got %d", s.Count())
+	}
+}
+
+func dump(t *testing.T, s store.Store) string {
+	t.Helper()
+	m := store.NewMapStore()
+	for _, id := range s.GetMembers() {
+		if len(s.GetIndex(id).StampedKeys) == 0 {
+			continue
+		}
+		for _, sk := range s.GetIndex(id).StampedKeys {
+			m.Set(s.Get(engine.KeyIDPair{ID: id, Key: sk.Key}))
+		}
+	}
+	d, err := m.Dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/dbenque/datafan/pkg/engine"
+)
+
+//MapStore is an in memory Store built on top of maps
+type MapStore struct {
+	sync.RWMutex
+	internal map[engine.ID]map[engine.Key]engine.Item
+}
+
+var _ Store = &MapStore{}
+
+func NewMapStore() *MapStore {
+	return &MapStore{
+		internal: map[engine.ID]map[engine.Key]engine.Item{},
+	}
+}
+
+func (m *MapStore) GetMembers() []engine.ID {
+	m.RLock()
+	defer m.RUnlock()
+	members := []engine.ID{}
+	for id := range m.internal {
+		members = append(members, id)
+	}
+	return members
+}
+func (m *MapStore) GetIndex(id engine.ID) engine.Index {
+	m.RLock()
+	defer m.RUnlock()
+
+	index := engine.Index{}
+	if s, ok := m.internal[id]; ok {
+		index.StampedKeys = []engine.StampedKey{}
+		for _, i := range s {
+			index.StampedKeys = append(index.StampedKeys, i.StampedKey())
+		}
+	}
+	return index
+}
+func (m *MapStore) MultiDelete(kps engine.KeyIDPairs) {
+	m.Lock()
+	defer m.Unlock()
+	for _, kp := range kps {
+		if s, ok := m.internal[kp.ID]; ok {
+			delete(s, kp.Key)
+		}
+	}
+}
+func (m *MapStore) Delete(kp engine.KeyIDPair) {
+	m.Lock()
+	defer m.Unlock()
+	if s, ok := m.internal[kp.ID]; ok {
+		delete(s, kp.Key)
+	}
+}
+func (m *MapStore) MultiSet(ilist engine.Items) {
+	m.Lock()
+	defer m.Unlock()
+	for _, i := range ilist {
+		m.set(i)
+	}
+}
+func (m *MapStore) Set(i engine.Item) {
+	m.Lock()
+	defer m.Unlock()
+	m.set(i)
+}
+func (m *MapStore) set(i engine.Item) {
+	id := i.OwnedBy()
+	s, ok := m.internal[id]
+	if !ok {
+		s = map[engine.Key]engine.Item{}
+		m.internal[id] = s
+	}
+	s[i.GetKey()] = i.DeepCopy()
+}
+func (m *MapStore) Get(kp engine.KeyIDPair) engine.Item {
+	m.RLock()
+	defer m.RUnlock()
+	if s, ok := m.internal[kp.ID]; ok {
+		if v, ok := s[kp.Key]; ok {
+			return v.DeepCopy()
+		}
+	}
+	return nil
+}
+
+//DeleteShard removes the owner id and all its items
+func (m *MapStore) DeleteShard(id engine.ID) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.internal, id)
+}
+
+//Dump returns a json representation of the content of the store
+func (m *MapStore) Dump() (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+	json, err := json.MarshalIndent(m.internal, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return string(json), nil
+}
+
+//Count returns the number of items in the store, all owners included
+func (m *MapStore) Count() (count int) {
+	m.RLock()
+	defer m.RUnlock()
+	for _, v := range m.internal {
+		count += len(v)
+	}
+	return count
+}
@@ -0,0 +1,25 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/dbenque/datafan/pkg/store"
+	"github.com/dbenque/datafan/pkg/store/storetest"
+)
+
+func TestMapStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store { return store.NewMapStore() })
+}
+
+func TestMapStoreCount(t *testing.T) {
+	s := store.NewMapStore()
+	s.Set(storetest.NewItem("M0", "a", "a"))
+	s.Set(storetest.NewItem("M1", "a", "a"))
+	s.Set(storetest.NewItem("M1", "b", "b"))
+	if c := s.Count(); c != 3 {
+		t.Fatalf("expected 3 items, got %d", c)
+	}
+	if _, err := s.Dump(); err != nil {
+		t.Fatal(err)
+	}
+}
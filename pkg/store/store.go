@@ -0,0 +1,19 @@
+package store
+
+import "github.com/dbenque/datafan/pkg/engine"
+
+//Store is the storage backing a LocalMember. Data is sharded per owner (engine.ID)
+//
+//A shard must remain listed by GetMembers once all its keys have been deleted: the empty index
+//it produces is what propagates the deletion of the last keys to the other members.
+//DeleteShard is the only way to remove a shard: it is used once its owner has retired from the mesh.
+type Store interface {
+	GetMembers() []engine.ID
+	GetIndex(id engine.ID) engine.Index
+	Delete(engine.KeyIDPair)
+	Set(engine.Item)
+	MultiSet(engine.Items)
+	MultiDelete(engine.KeyIDPairs)
+	Get(engine.KeyIDPair) engine.Item
+	DeleteShard(engine.ID)
+}
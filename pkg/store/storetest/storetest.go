@@ -0,0 +1,278 @@
+//Package storetest provides a conformance test suite for store.Store implementations
+package storetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbenque/datafan/pkg/engine"
+	"github.com/dbenque/datafan/pkg/store"
+)
+
+//Item is the engine.Item used by the suite
+type Item struct {
+	Key     engine.Key
+	Time    time.Time
+	Value   string
+	Owner   engine.ID
+	Expires time.Time
+}
+
+var _ engine.Item = &Item{}
+
+func NewItem(owner engine.ID, key engine.Key, value string) *Item {
+	return &Item{
+		Key:   key,
+		Value: value,
+		Owner: owner,
+		Time:  time.Now(),
+	}
+}
+
+func (i *Item) GetKey() engine.Key {
+	return i.Key
+}
+func (i *Item) StampedKey() engine.StampedKey {
+	return engine.StampedKey{Key: i.Key, Timestamp: i.Time, Expires: i.Expires}
+}
+func (i *Item) OwnedBy() engine.ID {
+	return i.Owner
+}
+func (i *Item) DeepCopy() engine.Item {
+	j := *i
+	return &j
+}
+func (i *Item) Stamp(t time.Time) engine.Item {
+	j := *i
+	j.Time = t
+	return &j
+}
+func (i *Item) Transfer(id engine.ID, t time.Time) engine.Item {
+	j := *i
+	j.Owner = id
+	j.Time = t
+	return &j
+}
+
+//Codec serializes Item in json, for stores that need to marshal their content
+type Codec struct{}
+
+var _ engine.Codec = Codec{}
+
+func (Codec) Marshal(i engine.Item) ([]byte, error) {
+	return json.Marshal(i)
+}
+func (Codec) Unmarshal(data []byte) (engine.Item, error) {
+	i := &Item{}
+	if err := json.Unmarshal(data, i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+//Factory returns a new empty Store
+type Factory func(t *testing.T) store.Store
+
+//Run executes the conformance suite against the stores returned by newStore
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		test func(*testing.T, store.Store)
+	}{
+		{name: "Empty", test: testEmpty},
+		{name: "SetGet", test: testSetGet},
+		{name: "GetReturnsCopy", test: testGetReturnsCopy},
+		{name: "Overwrite", test: testOverwrite},
+		{name: "MultiSet", test: testMultiSet},
+		{name: "Delete", test: testDelete},
+		{name: "MultiDelete", test: testMultiDelete},
+		{name: "EmptyShardStaysListed", test: testEmptyShardStaysListed},
+		{name: "DeleteShard", test: testDeleteShard},
+		{name: "Concurrent", test: testConcurrent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func testEmpty(t *testing.T, s store.Store) {
+	if m := s.GetMembers(); len(m) != 0 {
+		t.Fatalf("expected no members, got %v", m)
+	}
+	if i := s.GetIndex("M0"); len(i.StampedKeys) != 0 {
+		t.Fatalf("expected empty index, got %v", i.StampedKeys)
+	}
+	if i := s.Get(engine.KeyIDPair{ID: "M0", Key: "k"}); i != nil {
+		t.Fatalf("expected nil item, got %v", i)
+	}
+}
+
+func testSetGet(t *testing.T, s store.Store) {
+	item := NewItem("M0", "k", "v")
+	s.Set(item)
+	checkItem(t, s, item)
+	checkMembers(t, s, "M0")
+	checkIndex(t, s, "M0", item)
+}
+
+func testGetReturnsCopy(t *testing.T, s store.Store) {
+	item := NewItem("M0", "k", "v")
+	s.Set(item)
+	item.Value = "changed after set"
+	got := s.Get(engine.KeyIDPair{ID: "M0", Key: "k"}).(*Item)
+	if got.Value != "v" {
+		t.Fatalf("store kept a reference to the item given to Set")
+	}
+	got.Value = "changed after get"
+	if again := s.Get(engine.KeyIDPair{ID: "M0", Key: "k"}).(*Item); again.Value != "v" {
+		t.Fatalf("store returned a reference to its internal item")
+	}
+}
+
+func testOverwrite(t *testing.T, s store.Store) {
+	s.Set(NewItem("M0", "k", "v1"))
+	item := NewItem("M0", "k", "v2")
+	item.Time = item.Time.Add(time.Second)
+	s.Set(item)
+	checkItem(t, s, item)
+	checkIndex(t, s, "M0", item)
+}
+
+func testMultiSet(t *testing.T, s store.Store) {
+	items := engine.Items{}
+	for _, owner := range []engine.ID{"M0", "M1", "M2"} {
+		for k := 0; k < 5; k++ {
+			items = append(items, NewItem(owner, engine.Key(fmt.Sprintf("k%d", k)), fmt.Sprintf("%s-%d", owner, k)))
+		}
+	}
+	s.MultiSet(items)
+	checkMembers(t, s, "M0", "M1", "M2")
+	for _, i := range items {
+		checkItem(t, s, i.(*Item))
+	}
+	checkIndex(t, s, "M1", items[5:10]...)
+}
+
+func testDelete(t *testing.T, s store.Store) {
+	a, b := NewItem("M0", "a", "a"), NewItem("M0", "b", "b")
+	s.MultiSet(engine.Items{a, b})
+	s.Delete(engine.KeyIDPair{ID: "M0", Key: "a"})
+	if i := s.Get(engine.KeyIDPair{ID: "M0", Key: "a"}); i != nil {
+		t.Fatalf("deleted item still returned: %v", i)
+	}
+	checkItem(t, s, b)
+	checkIndex(t, s, "M0", b)
+	// deleting something that does not exist is a no-op
+	s.Delete(engine.KeyIDPair{ID: "M0", Key: "a"})
+	s.Delete(engine.KeyIDPair{ID: "unknown", Key: "a"})
+	checkIndex(t, s, "M0", b)
+}
+
+func testMultiDelete(t *testing.T, s store.Store) {
+	a, b, c := NewItem("M0", "a", "a"), NewItem("M0", "b", "b"), NewItem("M1", "c", "c")
+	s.MultiSet(engine.Items{a, b, c})
+	s.MultiDelete(engine.KeyIDPairs{{ID: "M0", Key: "a"}, {ID: "M1", Key: "c"}, {ID: "M2", Key: "x"}})
+	checkIndex(t, s, "M0", b)
+	checkIndex(t, s, "M1")
+}
+
+func testEmptyShardStaysListed(t *testing.T, s store.Store) {
+	s.Set(NewItem("M1", "k", "v"))
+	s.Delete(engine.KeyIDPair{ID: "M1", Key: "k"})
+	checkMembers(t, s, "M1")
+	checkIndex(t, s, "M1")
+}
+
+func testDeleteShard(t *testing.T, s store.Store) {
+	a, b, c := NewItem("M0", "a", "a"), NewItem("M0", "b", "b"), NewItem("M1", "c", "c")
+	s.MultiSet(engine.Items{a, b, c})
+	s.DeleteShard("M0")
+	checkMembers(t, s, "M1")
+	if i := s.Get(engine.KeyIDPair{ID: "M0", Key: "a"}); i != nil {
+		t.Fatalf("item of a deleted shard still returned: %v", i)
+	}
+	checkItem(t, s, c)
+	// deleting an unknown shard is a no-op
+	s.DeleteShard("unknown")
+	checkMembers(t, s, "M1")
+	// the owner can come back
+	s.Set(a)
+	checkMembers(t, s, "M0", "M1")
+	checkIndex(t, s, "M0", a)
+}
+
+func testConcurrent(t *testing.T, s store.Store) {
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			owner := engine.ID(fmt.Sprintf("M%d", w%2))
+			for k := 0; k < 50; k++ {
+				key := engine.Key(fmt.Sprintf("w%d-k%d", w, k))
+				s.Set(NewItem(owner, key, "v"))
+				s.Get(engine.KeyIDPair{ID: owner, Key: key})
+				s.GetIndex(owner)
+				s.GetMembers()
+				if k%2 == 0 {
+					s.Delete(engine.KeyIDPair{ID: owner, Key: key})
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	count := 0
+	for _, id := range s.GetMembers() {
+		count += len(s.GetIndex(id).StampedKeys)
+	}
+	if count != 8*25 {
+		t.Fatalf("expected %d items after concurrent writes, got %d", 8*25, count)
+	}
+}
+
+func checkItem(t *testing.T, s store.Store, expected *Item) {
+	t.Helper()
+	got := s.Get(engine.KeyIDPair{ID: expected.Owner, Key: expected.Key})
+	if got == nil {
+		t.Fatalf("item %s/%s not found", expected.Owner, expected.Key)
+	}
+	g := got.(*Item)
+	if g.Value != expected.Value || !g.Time.Equal(expected.Time) || g.Owner != expected.Owner {
+		t.Fatalf("item %s/%s: expected %v, got %v", expected.Owner, expected.Key, expected, g)
+	}
+}
+
+func checkMembers(t *testing.T, s store.Store, expected ...engine.ID) {
+	t.Helper()
+	got := s.GetMembers()
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("expected members %v, got %v", expected, got)
+	}
+}
+
+func checkIndex(t *testing.T, s store.Store, id engine.ID, expected ...engine.Item) {
+	t.Helper()
+	index := s.GetIndex(id)
+	want := engine.StampedKeys{}
+	for _, i := range expected {
+		want = append(want, i.StampedKey())
+	}
+	got := append(engine.StampedKeys{}, index.StampedKeys...)
+	sort.Sort(want)
+	sort.Sort(got)
+	if len(got) != len(want) {
+		t.Fatalf("index of %s: expected %v, got %v", id, want, got)
+	}
+	for i := range want {
+		if want[i].Key != got[i].Key || !want[i].Timestamp.Equal(got[i].Timestamp) {
+			t.Fatalf("index of %s: expected %v, got %v", id, want, got)
+		}
+	}
+}